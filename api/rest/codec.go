@@ -0,0 +1,331 @@
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ErrUnsupportedData is the error returned by a codec for data its format can
+// not represent, e.g. a map in XML. Respond answers it with 406.
+var ErrUnsupportedData = errors.New("the data can not be encoded in the negotiated format")
+
+// Codec marshals and decodes the documents of a media type.
+type Codec interface {
+	// MediaType returns the media type handled by the codec, e.g.
+	// application/json.
+	MediaType() string
+	// Marshal returns the encoding of v. The error wraps ErrUnsupportedData
+	// when the format can not represent v.
+	Marshal(v interface{}) ([]byte, error)
+	// Decode reads a single value from r into v, rejecting unknown fields
+	// when the format allows it.
+	Decode(r io.Reader, v interface{}) error
+}
+
+// Codecs is a registry of codecs keyed by media type. The first registered
+// codec is the default one, used when the client accepts any media type.
+type Codecs struct {
+	codecs []Codec
+	byType map[string]Codec
+}
+
+// NewCodecs creates a registry with the given codecs.
+func NewCodecs(codecs ...Codec) *Codecs {
+	c := &Codecs{
+		byType: make(map[string]Codec, len(codecs)),
+	}
+
+	for _, codec := range codecs {
+		c.Register(codec)
+	}
+
+	return c
+}
+
+// defaultCodecs is the registry used when none is configured.
+var defaultCodecs = NewCodecs(JSONCodec{})
+
+// Register adds the codec to the registry, replacing any codec registered for
+// the same media type.
+func (c *Codecs) Register(codec Codec) {
+	mt := codec.MediaType()
+	if _, ok := c.byType[mt]; ok {
+		for i := range c.codecs {
+			if c.codecs[i].MediaType() == mt {
+				c.codecs[i] = codec
+			}
+		}
+	} else {
+		c.codecs = append(c.codecs, codec)
+	}
+
+	c.byType[mt] = codec
+}
+
+// Lookup returns the codec registered for the media type of a Content-Type
+// header value.
+func (c *Codecs) Lookup(contentType string) (Codec, bool) {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+
+	codec, ok := c.byType[mt]
+
+	return codec, ok
+}
+
+// Default returns the default codec of the registry.
+func (c *Codecs) Default() Codec {
+	if len(c.codecs) == 0 {
+		return JSONCodec{}
+	}
+
+	return c.codecs[0]
+}
+
+// Negotiate returns the codec preferred by the client according to the given
+// Accept header value. Quality values and wildcards are honored and, on a tie,
+// the codec registered first wins. An empty header accepts the default codec.
+// It returns false when no codec is acceptable.
+func (c *Codecs) Negotiate(accept string) (Codec, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return c.Default(), true
+	}
+
+	ranges := parseAccept(accept)
+
+	var best Codec
+	var bestQ float64
+	for _, codec := range c.codecs {
+		if q := quality(ranges, codec.MediaType()); q > bestQ {
+			best, bestQ = codec, q
+		}
+	}
+
+	return best, best != nil
+}
+
+// mediaRange is a media range of an Accept header.
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept parses the media ranges of an Accept header value. Invalid
+// ranges are ignored.
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		typ, subtype, ok := strings.Cut(mt, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+
+		ranges = append(ranges, mediaRange{typ: typ, subtype: structuredSyntax(subtype), q: q})
+	}
+
+	// The most specific ranges are matched first.
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return specificity(ranges[i]) > specificity(ranges[j])
+	})
+
+	return ranges
+}
+
+// specificity ranks the media ranges, exact types being the most specific.
+func specificity(mr mediaRange) int {
+	switch {
+	case mr.typ == "*":
+		return 0
+	case mr.subtype == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// quality returns the quality value of the media type from the most specific
+// matching range, or 0 when none matches.
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	subtype = structuredSyntax(subtype)
+
+	for _, mr := range ranges {
+		if (mr.typ == "*" || mr.typ == typ) && (mr.subtype == "*" || mr.subtype == subtype) {
+			return mr.q
+		}
+	}
+
+	return 0
+}
+
+// structuredSyntax returns the structured syntax suffix of a subtype, e.g.
+// json for problem+json, so such media types match the codec of the syntax.
+func structuredSyntax(subtype string) string {
+	if i := strings.LastIndex(subtype, "+"); i >= 0 {
+		return subtype[i+1:]
+	}
+
+	return subtype
+}
+
+// contentType returns the content type of a document built by an Encoder for
+// a JSON representation once encoded with the codec, e.g.
+// application/problem+json becomes application/problem+xml.
+func contentType(encoderType string, codec Codec) string {
+	switch {
+	case encoderType == "application/json":
+		return codec.MediaType()
+	case strings.HasSuffix(encoderType, "+json"):
+		_, subtype, _ := strings.Cut(codec.MediaType(), "/")
+		return strings.TrimSuffix(encoderType, "json") + structuredSyntax(subtype)
+	default:
+		return encoderType
+	}
+}
+
+// documentMapper is implemented by the documents whose members are not known
+// at compile time, for the codecs which do not use json.Marshaler.
+type documentMapper interface {
+	document() map[string]interface{}
+}
+
+// JSONCodec is the codec for application/json.
+type JSONCodec struct{}
+
+// MediaType implements the Codec interface.
+func (JSONCodec) MediaType() string { return "application/json" }
+
+// Marshal implements the Codec interface.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Decode implements the Codec interface. Only a single JSON value is allowed
+// in the body.
+func (JSONCodec) Decode(r io.Reader, v interface{}) error {
+	d := json.NewDecoder(r)
+	d.DisallowUnknownFields()
+
+	if err := d.Decode(v); err != nil {
+		return err
+	}
+
+	if err := d.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return errMultipleValues
+	}
+
+	return nil
+}
+
+// XMLCodec is the codec for application/xml. Maps can not be encoded in XML,
+// the responses holding one are answered with 406.
+type XMLCodec struct{}
+
+// MediaType implements the Codec interface.
+func (XMLCodec) MediaType() string { return "application/xml" }
+
+// Marshal implements the Codec interface.
+func (XMLCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		var ute *xml.UnsupportedTypeError
+		if errors.As(err, &ute) {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedData, err)
+		}
+
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), b...), nil
+}
+
+// Decode implements the Codec interface.
+func (XMLCodec) Decode(r io.Reader, v interface{}) error {
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// MsgPackCodec is the codec for application/msgpack. The json struct tags are
+// used for the field names and their omitempty option.
+type MsgPackCodec struct{}
+
+// MediaType implements the Codec interface.
+func (MsgPackCodec) MediaType() string { return "application/msgpack" }
+
+// Marshal implements the Codec interface.
+func (MsgPackCodec) Marshal(v interface{}) ([]byte, error) {
+	if dm, ok := v.(documentMapper); ok {
+		v = dm.document()
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode implements the Codec interface.
+func (MsgPackCodec) Decode(r io.Reader, v interface{}) error {
+	dec := msgpack.NewDecoder(r)
+	dec.SetCustomStructTag("json")
+	dec.DisallowUnknownFields(true)
+
+	return dec.Decode(v)
+}
+
+// cborDecMode rejects unknown fields when decoding CBOR.
+var cborDecMode, _ = cbor.DecOptions{
+	ExtraReturnErrors: cbor.ExtraDecErrorUnknownField,
+}.DecMode()
+
+// CBORCodec is the codec for application/cbor. The json struct tags are used
+// for the field names.
+type CBORCodec struct{}
+
+// MediaType implements the Codec interface.
+func (CBORCodec) MediaType() string { return "application/cbor" }
+
+// Marshal implements the Codec interface.
+func (CBORCodec) Marshal(v interface{}) ([]byte, error) {
+	if dm, ok := v.(documentMapper); ok {
+		v = dm.document()
+	}
+
+	return cbor.Marshal(v)
+}
+
+// Decode implements the Codec interface.
+func (CBORCodec) Decode(r io.Reader, v interface{}) error {
+	return cborDecMode.NewDecoder(r).Decode(v)
+}
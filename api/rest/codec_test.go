@@ -0,0 +1,321 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestCodecs_Negotiate(t *testing.T) {
+	codecs := NewCodecs(JSONCodec{}, XMLCodec{}, MsgPackCodec{}, CBORCodec{})
+
+	testCases := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{name: "Empty", accept: "", expected: "application/json"},
+		{name: "Any", accept: "*/*", expected: "application/json"},
+		{name: "Exact", accept: "application/cbor", expected: "application/cbor"},
+		{name: "Parameters", accept: "application/xml; charset=utf-8", expected: "application/xml"},
+		{name: "Quality", accept: "application/json;q=0.5, application/msgpack", expected: "application/msgpack"},
+		{name: "Specific Over Wildcard", accept: "application/*;q=0.9, application/json;q=0.1", expected: "application/xml"},
+		{name: "Excluded", accept: "application/json;q=0, */*;q=0.5", expected: "application/xml"},
+		{name: "Structured Syntax", accept: "application/problem+xml", expected: "application/xml"},
+		{name: "Browser", accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", expected: "application/xml"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			codec, ok := codecs.Negotiate(tc.accept)
+			if !ok {
+				t.Fatal("Expected a codec to be negotiated")
+			}
+			if codec.MediaType() != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, codec.MediaType())
+			}
+		})
+	}
+
+	t.Run("No Match", func(t *testing.T) {
+		for _, accept := range []string{"text/html", "application/json;q=0", "text/*"} {
+			if codec, ok := codecs.Negotiate(accept); ok {
+				t.Errorf("Expected no codec for %q, got %s", accept, codec.MediaType())
+			}
+		}
+	})
+}
+
+func TestCodecs_Register(t *testing.T) {
+	codecs := NewCodecs(JSONCodec{}, XMLCodec{})
+	codecs.Register(XMLCodec{})
+
+	if len(codecs.codecs) != 2 {
+		t.Errorf("Expected codecs to be replaced, got %d codecs", len(codecs.codecs))
+	}
+	if codec, ok := codecs.Lookup("application/xml; charset=utf-8"); !ok || codec.MediaType() != "application/xml" {
+		t.Error("Expected to find the XML codec")
+	}
+	if _, ok := codecs.Lookup("application/cbor"); ok {
+		t.Error("Expected not to find the CBOR codec")
+	}
+}
+
+// codecSite is the data used to test the codecs.
+type codecSite struct {
+	Name string `json:"name" xml:"name"`
+	Size int    `json:"size" xml:"size"`
+}
+
+func TestRespond_Negotiation(t *testing.T) {
+	shutdown := make(chan os.Signal, 1)
+	api := NewWithOptions(shutdown, WithCodecs(JSONCodec{}, XMLCodec{}, MsgPackCodec{}, CBORCodec{}))
+	api.Handle(http.MethodGet, "/sites/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		err := Respond(ctx, w, codecSite{Name: "site", Size: 3}, http.StatusOK)
+		if re := GetRequestError(err); re != nil {
+			_ = SetIsError(ctx)
+			return Respond(ctx, w, re.Response(), re.StatusCode)
+		}
+		return err
+	})
+	api.Handle(http.MethodGet, "/problems/{id}", respondErrorHandler(
+		NewRequestError(errors.New("site not found"), http.StatusNotFound, "not_found"),
+	), UseEncoder(ProblemEncoder{}))
+	api.Handle(http.MethodGet, "/errors/{id}", respondErrorHandler(
+		NewRequestError(errors.New("site not found"), http.StatusNotFound, "not_found"),
+	))
+	api.Handle(http.MethodGet, "/empty", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return Respond(ctx, w, codecSite{}, http.StatusOK)
+	})
+	api.Handle(http.MethodGet, "/labels", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		err := Respond(ctx, w, map[string]string{"env": "prod"}, http.StatusOK)
+		if re := GetRequestError(err); re != nil {
+			_ = SetIsError(ctx)
+			return Respond(ctx, w, re.Response(), re.StatusCode)
+		}
+		return err
+	})
+
+	serve := func(target, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+
+		return rr
+	}
+
+	t.Run("XML", func(t *testing.T) {
+		rr := serve("/sites/1", "application/xml")
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/xml" {
+			t.Errorf("Expected Content-Type 'application/xml', got '%s'", ct)
+		}
+		if vary := rr.Header().Get("Vary"); vary != "Accept" {
+			t.Errorf("Expected Vary 'Accept', got '%s'", vary)
+		}
+
+		var resp struct {
+			Success bool      `xml:"success"`
+			Data    codecSite `xml:"data"`
+		}
+		if err := xml.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if !resp.Success || resp.Data.Name != "site" || resp.Data.Size != 3 {
+			t.Errorf("Unexpected response: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("MessagePack", func(t *testing.T) {
+		rr := serve("/sites/1", "application/msgpack")
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/msgpack" {
+			t.Errorf("Expected Content-Type 'application/msgpack', got '%s'", ct)
+		}
+
+		var resp map[string]interface{}
+		if err := msgpack.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		data, _ := resp["data"].(map[string]interface{})
+		if resp["success"] != true || data["name"] != "site" {
+			t.Errorf("Unexpected response: %v", resp)
+		}
+	})
+
+	t.Run("MessagePack Error", func(t *testing.T) {
+		rr := serve("/errors/1", "application/msgpack")
+
+		var resp map[string]interface{}
+		if err := msgpack.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		errs, _ := resp["errors"].(map[string]interface{})
+		if resp["success"] != false || resp["timestamp"] == nil || errs["code"] != "not_found" {
+			t.Errorf("Unexpected response: %v", resp)
+		}
+	})
+
+	t.Run("MessagePack Zero Values", func(t *testing.T) {
+		rr := serve("/empty", "application/msgpack")
+
+		var resp map[string]interface{}
+		if err := msgpack.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		data, _ := resp["data"].(map[string]interface{})
+		if _, ok := data["name"]; !ok || data["size"] == nil {
+			t.Errorf("Expected the zero values to be sent, got %v", resp)
+		}
+		if _, ok := resp["errors"]; ok {
+			t.Errorf("Expected the omitempty members to be left out, got %v", resp)
+		}
+	})
+
+	t.Run("XML Unsupported Data", func(t *testing.T) {
+		rr := serve("/labels", "application/xml")
+
+		if rr.Code != http.StatusNotAcceptable {
+			t.Errorf("Expected status %v, got %v", http.StatusNotAcceptable, rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), "<code>"+CodeNotAcceptable+"</code>") {
+			t.Errorf("Unexpected response: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("CBOR", func(t *testing.T) {
+		rr := serve("/sites/1", "application/cbor")
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/cbor" {
+			t.Errorf("Expected Content-Type 'application/cbor', got '%s'", ct)
+		}
+
+		var resp struct {
+			Success bool      `json:"success"`
+			Data    codecSite `json:"data"`
+		}
+		if err := cbor.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if !resp.Success || resp.Data.Name != "site" || resp.Data.Size != 3 {
+			t.Errorf("Unexpected response: %+v", resp)
+		}
+	})
+
+	t.Run("Not Acceptable", func(t *testing.T) {
+		rr := serve("/sites/1", "text/html")
+
+		if rr.Code != http.StatusNotAcceptable {
+			t.Errorf("Expected status %v, got %v", http.StatusNotAcceptable, rr.Code)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected Content-Type 'application/json', got '%s'", ct)
+		}
+		if !strings.Contains(rr.Body.String(), CodeNotAcceptable) {
+			t.Errorf("Unexpected response: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Problem XML", func(t *testing.T) {
+		rr := serve("/problems/1", "application/xml")
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/problem+xml" {
+			t.Errorf("Expected Content-Type 'application/problem+xml', got '%s'", ct)
+		}
+
+		var p struct {
+			XMLName xml.Name
+			Status  int    `xml:"status"`
+			Detail  string `xml:"detail"`
+			Code    string `xml:"code"`
+		}
+		if err := xml.Unmarshal(rr.Body.Bytes(), &p); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if p.XMLName.Space != "urn:ietf:rfc:7807" || p.Status != http.StatusNotFound || p.Detail != "site not found" || p.Code != "not_found" {
+			t.Errorf("Unexpected problem: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Problem CBOR", func(t *testing.T) {
+		rr := serve("/problems/1", "application/cbor")
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/problem+cbor" {
+			t.Errorf("Expected Content-Type 'application/problem+cbor', got '%s'", ct)
+		}
+
+		var p map[string]interface{}
+		if err := cbor.Unmarshal(rr.Body.Bytes(), &p); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if p["detail"] != "site not found" || p["code"] != "not_found" {
+			t.Errorf("Unexpected problem: %v", p)
+		}
+	})
+}
+
+func TestDecode_Codecs(t *testing.T) {
+	shutdown := make(chan os.Signal, 1)
+	api := NewWithOptions(shutdown, WithCodecs(JSONCodec{}, XMLCodec{}, MsgPackCodec{}, CBORCodec{}))
+
+	var decoded codecSite
+	api.Handle(http.MethodPost, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		decoded = codecSite{}
+		if err := Decode(r, &decoded); err != nil {
+			re := GetRequestError(err)
+			w.WriteHeader(re.StatusCode)
+			return nil
+		}
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	})
+
+	site := codecSite{Name: "site", Size: 3}
+	xmlBody, _ := XMLCodec{}.Marshal(site)
+	msgpackBody, _ := MsgPackCodec{}.Marshal(site)
+	cborBody, _ := CBORCodec{}.Marshal(site)
+	unknownBody, _ := CBORCodec{}.Marshal(map[string]interface{}{"name": "site", "color": "red"})
+
+	testCases := []struct {
+		name           string
+		contentType    string
+		body           []byte
+		expectedStatus int
+	}{
+		{name: "JSON", contentType: "application/json", body: []byte(`{"name":"site","size":3}`), expectedStatus: http.StatusCreated},
+		{name: "XML", contentType: "application/xml", body: xmlBody, expectedStatus: http.StatusCreated},
+		{name: "MessagePack", contentType: "application/msgpack", body: msgpackBody, expectedStatus: http.StatusCreated},
+		{name: "CBOR", contentType: "application/cbor", body: cborBody, expectedStatus: http.StatusCreated},
+		{name: "CBOR Unknown Field", contentType: "application/cbor", body: unknownBody, expectedStatus: http.StatusBadRequest},
+		{name: "Unsupported", contentType: "text/plain", body: []byte("site"), expectedStatus: http.StatusUnsupportedMediaType},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/sites", bytes.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("Expected status %v, got %v", tc.expectedStatus, rr.Code)
+			}
+			if tc.expectedStatus == http.StatusCreated && (decoded.Name != "site" || decoded.Size != 3) {
+				t.Errorf("Unexpected value: %+v", decoded)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package rest
 
 import (
 	"context"
+	"net/http"
+	"time"
 )
 
 // ctxKey represents the type of value for the context key.
@@ -12,9 +14,27 @@ const key ctxKey = 1
 
 // ContextValues represent state for each request.
 type ContextValues struct {
-	StatusCode int
-	IsError    bool
-	Path       string
+	StatusCode      int
+	IsError         bool
+	Path            string
+	RequestID       string
+	BytesWritten    int64
+	HeadersSent     bool
+	TimeToFirstByte time.Duration
+	Principal       *Principal
+
+	// request is the request being served.
+	request *http.Request
+	// encoder builds the documents sent by Respond.
+	encoder Encoder
+	// codecs holds the formats supported for the requests and responses.
+	codecs *Codecs
+	// etags is the kind of ETags sent by Respond.
+	etags ETagMode
+	// pagination is added to the envelope of a paginated response.
+	pagination *Pagination
+	// errorHandler is the error handler of the API.
+	errorHandler ErrorHandler
 }
 
 // GetContextValues returns the values from the context.
@@ -27,6 +47,21 @@ func GetContextValues(ctx context.Context) (*ContextValues, error) {
 	return v, nil
 }
 
+// CopyContextValues returns a context derived from ctx holding a copy of its
+// values, for a part of the handler chain running concurrently with the rest
+// of it, e.g. a handler cut by a timeout. The values written to the response
+// are still recorded in the original values.
+func CopyContextValues(ctx context.Context) (context.Context, *ContextValues, error) {
+	v, ok := ctx.Value(key).(*ContextValues)
+	if !ok {
+		return ctx, nil, ErrMissingContext
+	}
+
+	c := *v
+
+	return context.WithValue(ctx, key, &c), &c, nil
+}
+
 // SetStatusCode sets the status code back into the context.
 func SetStatusCode(ctx context.Context, statusCode int) error {
 	v, ok := ctx.Value(key).(*ContextValues)
@@ -62,3 +97,64 @@ func SetPath(ctx context.Context, path string) error {
 
 	return nil
 }
+
+// SetRequestID sets the request ID back into the context.
+func SetRequestID(ctx context.Context, requestID string) error {
+	v, ok := ctx.Value(key).(*ContextValues)
+	if !ok {
+		return ErrMissingContext
+	}
+
+	v.RequestID = requestID
+
+	return nil
+}
+
+// GetRequestID returns the request ID from the context. It returns an empty
+// string when the context does not hold the request values, which makes it
+// safe to use from code shared with non HTTP entry points.
+func GetRequestID(ctx context.Context) string {
+	v, ok := ctx.Value(key).(*ContextValues)
+	if !ok {
+		return ""
+	}
+
+	return v.RequestID
+}
+
+// SetPrincipal sets the authenticated principal back into the context.
+func SetPrincipal(ctx context.Context, p *Principal) error {
+	v, ok := ctx.Value(key).(*ContextValues)
+	if !ok {
+		return ErrMissingContext
+	}
+
+	v.Principal = p
+
+	return nil
+}
+
+// GetPrincipal returns the authenticated principal from the context. It
+// returns false when the request is not authenticated.
+func GetPrincipal(ctx context.Context) (*Principal, bool) {
+	v, ok := ctx.Value(key).(*ContextValues)
+	if !ok || v.Principal == nil {
+		return nil, false
+	}
+
+	return v.Principal, true
+}
+
+// ReportError hands the error over to the error handler of the API. It is
+// meant for the errors which can not be returned through the handler chain,
+// e.g. of a handler still running once its request completed.
+func ReportError(ctx context.Context, r *http.Request, err error) error {
+	v, ok := ctx.Value(key).(*ContextValues)
+	if !ok || v.errorHandler == nil {
+		return ErrMissingContext
+	}
+
+	v.errorHandler(ctx, r, err)
+
+	return nil
+}
@@ -5,7 +5,9 @@ package rest
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -76,3 +78,86 @@ func TestSetPath(t *testing.T) {
 		t.Errorf("Expected path '/new-path', got '%s'", v.Path)
 	}
 }
+
+func TestSetRequestID(t *testing.T) {
+	ctx := context.WithValue(context.Background(), key, &ContextValues{})
+
+	err := SetRequestID(ctx, "abc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if id := GetRequestID(ctx); id != "abc" {
+		t.Errorf("Expected request ID 'abc', got '%s'", id)
+	}
+
+	if id := GetRequestID(context.Background()); id != "" {
+		t.Errorf("Expected empty request ID, got '%s'", id)
+	}
+}
+
+func TestSetPrincipal(t *testing.T) {
+	ctx := context.WithValue(context.Background(), key, &ContextValues{})
+
+	if _, ok := GetPrincipal(ctx); ok {
+		t.Error("Expected no principal")
+	}
+
+	err := SetPrincipal(ctx, &Principal{Subject: "user-1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	p, ok := GetPrincipal(ctx)
+	if !ok || p.Subject != "user-1" {
+		t.Errorf("Expected principal 'user-1', got %+v", p)
+	}
+
+	if _, ok := GetPrincipal(context.Background()); ok {
+		t.Error("Expected no principal without the context values")
+	}
+}
+
+func TestCopyContextValues(t *testing.T) {
+	ctx := context.WithValue(context.Background(), key, &ContextValues{RequestID: "abc"})
+
+	cctx, c, err := CopyContextValues(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_ = SetIsError(cctx)
+	if !c.IsError || c.RequestID != "abc" {
+		t.Errorf("Expected a copy of the values, got %+v", c)
+	}
+
+	v, _ := GetContextValues(ctx)
+	if v.IsError {
+		t.Error("Expected the original values to be left untouched")
+	}
+
+	if _, _, err := CopyContextValues(context.Background()); err != ErrMissingContext {
+		t.Errorf("Expected error %v, got %v", ErrMissingContext, err)
+	}
+}
+
+func TestReportError(t *testing.T) {
+	var reported error
+	ctx := context.WithValue(context.Background(), key, &ContextValues{
+		errorHandler: func(ctx context.Context, r *http.Request, err error) {
+			reported = err
+		},
+	})
+
+	err := errors.New("late failure")
+	if rErr := ReportError(ctx, httptest.NewRequest(http.MethodGet, "/", nil), err); rErr != nil {
+		t.Fatalf("Unexpected error: %v", rErr)
+	}
+	if reported != err {
+		t.Errorf("Expected the error to be handed to the error handler, got %v", reported)
+	}
+
+	if rErr := ReportError(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil), err); rErr != ErrMissingContext {
+		t.Errorf("Expected error %v, got %v", ErrMissingContext, rErr)
+	}
+}
@@ -0,0 +1,148 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodyBytes is the default maximum size of a request body.
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// errMultipleValues is returned by the codecs when the body contains more
+// than one value.
+var errMultipleValues = errors.New("body must only contain a single value")
+
+// Validator is implemented by the request values which can validate
+// themselves once decoded. Returning FieldErrors lists every invalid field in
+// the response.
+type Validator interface {
+	Validate() error
+}
+
+// DecodeOption configures Decode.
+type DecodeOption interface {
+	apply(*decodeOptions)
+}
+
+// decodeOptions holds the settings of Decode.
+type decodeOptions struct {
+	maxBodyBytes int64
+}
+
+type decodeOptionFunc func(*decodeOptions)
+
+func (f decodeOptionFunc) apply(o *decodeOptions) { f(o) }
+
+// WithMaxBodyBytes sets the maximum size of the request body.
+func WithMaxBodyBytes(n int64) DecodeOption {
+	return decodeOptionFunc(func(o *decodeOptions) {
+		if n > 0 {
+			o.maxBodyBytes = n
+		}
+	})
+}
+
+// Decode reads the body of the request into the provided value using the
+// codec registered in the API for its Content-Type, JSON by default. The body
+// is limited in size and unknown fields are rejected. If the value implements
+// Validator, it is validated once decoded.
+//
+// The returned errors are RequestError values, so they can be returned as is
+// by the handlers:
+//
+//	415: the content type is not supported
+//	413: the body is too large
+//	400: the body is malformed
+//	422: the value failed the validation
+func Decode(r *http.Request, val interface{}, opts ...DecodeOption) error {
+	o := decodeOptions{
+		maxBodyBytes: DefaultMaxBodyBytes,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	codecs := defaultCodecs
+	if v, err := GetContextValues(r.Context()); err == nil && v.codecs != nil {
+		codecs = v.codecs
+	}
+
+	ct := r.Header.Get("Content-Type")
+	codec, ok := codecs.Lookup(ct)
+	if !ok {
+		return NewRequestError(
+			fmt.Errorf("unsupported content type %q", ct),
+			http.StatusUnsupportedMediaType,
+			CodeUnsupportedMediaType,
+		)
+	}
+
+	if err := codec.Decode(http.MaxBytesReader(nil, r.Body, o.maxBodyBytes), val); err != nil {
+		return decodeError(err)
+	}
+
+	if v, ok := val.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return NewRequestError(err, http.StatusUnprocessableEntity, CodeValidation)
+		}
+	}
+
+	return nil
+}
+
+// decodeError converts the error returned by the codec into a RequestError.
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return NewRequestError(
+			fmt.Errorf("body must not be larger than %d bytes", maxBytesErr.Limit),
+			http.StatusRequestEntityTooLarge,
+			CodeRequestTooLarge,
+		)
+
+	case errors.As(err, &syntaxErr):
+		return NewRequestError(
+			fmt.Errorf("body contains malformed JSON at offset %d", syntaxErr.Offset),
+			http.StatusBadRequest,
+			CodeInvalidBody,
+		)
+
+	case errors.Is(err, errMultipleValues):
+		return NewRequestError(err, http.StatusBadRequest, CodeInvalidBody)
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return NewRequestError(errors.New("body is malformed"), http.StatusBadRequest, CodeInvalidBody)
+
+	case errors.Is(err, io.EOF):
+		return NewRequestError(errors.New("body must not be empty"), http.StatusBadRequest, CodeInvalidBody)
+
+	case errors.As(err, &typeErr):
+		return NewRequestError(
+			FieldErrors{{Field: typeErr.Field, Message: fmt.Sprintf("must be of type %s", typeErr.Type)}},
+			http.StatusBadRequest,
+			CodeInvalidBody,
+		)
+
+	// The decoder has no typed error for unknown fields.
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+
+		return NewRequestError(
+			FieldErrors{{Field: field, Message: "unknown field"}},
+			http.StatusBadRequest,
+			CodeInvalidBody,
+		)
+
+	default:
+		return NewRequestError(fmt.Errorf("body is malformed: %w", err), http.StatusBadRequest, CodeInvalidBody)
+	}
+}
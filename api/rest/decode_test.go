@@ -0,0 +1,208 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testSite is a request value validating itself.
+type testSite struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+	Size  int    `json:"size"`
+}
+
+// Validate implements the Validator interface.
+func (s testSite) Validate() error {
+	var fe FieldErrors
+	if s.Name == "" {
+		fe = append(fe, FieldError{Field: "name", Message: "name is required"})
+	}
+	if s.Owner == "" {
+		fe = append(fe, FieldError{Field: "owner", Message: "owner is required"})
+	}
+	if len(fe) > 0 {
+		return fe
+	}
+
+	return nil
+}
+
+// newDecodeRequest returns a request with the given body and content type.
+func newDecodeRequest(body string, contentType string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/sites", strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+
+	return r
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		var s testSite
+		r := newDecodeRequest(`{"name":"site","owner":"me","size":3}`, "application/json; charset=utf-8")
+		if err := Decode(r, &s); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if s.Name != "site" || s.Owner != "me" || s.Size != 3 {
+			t.Errorf("Unexpected value: %+v", s)
+		}
+	})
+
+	testCases := []struct {
+		name           string
+		body           string
+		contentType    string
+		opts           []DecodeOption
+		expectedStatus int
+		expectedCode   string
+		expectedFields []FieldError
+	}{
+		{
+			name:           "Missing Content Type",
+			body:           `{"name":"site","owner":"me"}`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   CodeUnsupportedMediaType,
+		},
+		{
+			name:           "Unsupported Content Type",
+			body:           `name=site`,
+			contentType:    "application/x-www-form-urlencoded",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   CodeUnsupportedMediaType,
+		},
+		{
+			name:           "Too Large",
+			body:           `{"name":"site","owner":"me"}`,
+			contentType:    "application/json",
+			opts:           []DecodeOption{WithMaxBodyBytes(10)},
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   CodeRequestTooLarge,
+		},
+		{
+			name:           "Malformed",
+			body:           `{"name":"site",}`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidBody,
+		},
+		{
+			name:           "Empty",
+			body:           ``,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidBody,
+		},
+		{
+			name:           "Multiple Values",
+			body:           `{"name":"site","owner":"me"}{}`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidBody,
+		},
+		{
+			name:           "Unknown Field",
+			body:           `{"name":"site","owner":"me","color":"red"}`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidBody,
+			expectedFields: []FieldError{{Field: "color", Message: "unknown field"}},
+		},
+		{
+			name:           "Wrong Type",
+			body:           `{"name":"site","owner":"me","size":"big"}`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidBody,
+			expectedFields: []FieldError{{Field: "size", Message: "must be of type int"}},
+		},
+		{
+			name:           "Validation",
+			body:           `{"size":3}`,
+			contentType:    "application/json",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   CodeValidation,
+			expectedFields: []FieldError{
+				{Field: "name", Message: "name is required"},
+				{Field: "owner", Message: "owner is required"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var s testSite
+			err := Decode(newDecodeRequest(tc.body, tc.contentType), &s, tc.opts...)
+
+			re := GetRequestError(err)
+			if re == nil {
+				t.Fatalf("Expected a request error, got %v", err)
+			}
+			if re.StatusCode != tc.expectedStatus || re.Code != tc.expectedCode {
+				t.Errorf("Unexpected request error: %+v", re)
+			}
+
+			er := re.Response()
+			if len(er.Fields) != len(tc.expectedFields) {
+				t.Fatalf("Unexpected fields: %+v", er.Fields)
+			}
+			for i, f := range tc.expectedFields {
+				if er.Fields[i] != f {
+					t.Errorf("Unexpected field error: got %+v want %+v", er.Fields[i], f)
+				}
+			}
+		})
+	}
+
+	t.Run("Validation Error", func(t *testing.T) {
+		v := validatorFunc(func() error { return errors.New("dates are overlapping") })
+		err := Decode(newDecodeRequest(`{}`, "application/json"), &v)
+
+		re := GetRequestError(err)
+		if re == nil || re.StatusCode != http.StatusUnprocessableEntity || re.Error() != "dates are overlapping" {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	})
+}
+
+// validatorFunc is a request value using a function as validation.
+type validatorFunc func() error
+
+// Validate implements the Validator interface.
+func (f *validatorFunc) Validate() error { return (*f)() }
+
+// UnmarshalJSON accepts any JSON object.
+func (f *validatorFunc) UnmarshalJSON([]byte) error { return nil }
+
+func TestDecodeResponse(t *testing.T) {
+	ctx := context.WithValue(context.Background(), key, &ContextValues{IsError: true})
+	err := Decode(newDecodeRequest(`{}`, "application/json"), &testSite{})
+
+	rr := httptest.NewRecorder()
+	if err := Respond(ctx, rr, GetRequestError(err).Response(), http.StatusUnprocessableEntity); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var resp struct {
+		Errors ErrorResponse `json:"errors"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	expected := `{"code":"validation_failed","message":"name: name is required; owner: owner is required","fields":[{"field":"name","message":"name is required"},{"field":"owner","message":"owner is required"}]}`
+	got, _ := json.Marshal(resp.Errors)
+	if string(got) != expected {
+		t.Errorf("Unexpected errors: got %s want %s", got, expected)
+	}
+}
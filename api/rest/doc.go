@@ -1,9 +1,19 @@
 // Package rest provides the wrapper for routing purposes.
 //
 // It provides a way to set the routes and the handlers for the application.
+// It provides a way to group the routes under a path prefix.
 // It provides a way to set the middleware for the application.
+// It provides a way to decode and validate the request body.
 // It provides a way to respond to the client.
+// It provides a way to paginate the collections sent to the client.
+// It provides a way to tag the responses with ETags and answer the
+// conditional requests.
+// It provides a way to stream large collections to the client.
+// It provides a way to push Server-Sent Events to the client.
 // It provides a way to respond with an error to the client.
+// It provides a way to choose the response format, e.g. RFC 7807 problems.
 // It provides a way to set and get the context values.
+// It provides a way to run the server with a graceful shutdown.
+// It provides a way to generate the OpenAPI 3.1 document of the routes.
 
 package rest
@@ -0,0 +1,72 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Encoder builds the document sent to the client by Respond.
+type Encoder interface {
+	// Encode returns the document to be marshaled for the given data and
+	// status code, along with its content type for a JSON representation.
+	// The content type is adapted to the codec negotiated with the client,
+	// e.g. application/problem+json becomes application/problem+xml. The
+	// data is usually an ErrorResponse when the request failed.
+	Encode(ctx context.Context, data interface{}, statusCode int) (doc interface{}, contentType string)
+}
+
+// EnvelopeEncoder wraps the data in the Response envelope. It is the default
+// encoder of the API.
+type EnvelopeEncoder struct{}
+
+// Encode implements the Encoder interface.
+func (EnvelopeEncoder) Encode(ctx context.Context, data interface{}, _ int) (interface{}, string) {
+	v, err := GetContextValues(ctx)
+	if err != nil {
+		v = &ContextValues{}
+	}
+
+	// Create the response object to be sent back to the client.
+	r := Response{
+		Success:   !v.IsError,
+		Timestamp: time.Now().UTC().Unix(),
+		RequestID: v.RequestID,
+	}
+
+	// Check if the data is an error or not
+	// If it is not an error, then set the data
+	// If it is an error, then set the error
+	if !v.IsError {
+		r.Data = data
+		r.Pagination = v.pagination
+	} else {
+		r.Errors = data
+	}
+
+	return r, "application/json"
+}
+
+// UseEncoder selects the encoder used by Respond for the requests going
+// through this middleware. It is meant to select the encoder of a route group
+// or a single route.
+func UseEncoder(enc Encoder) Middleware {
+	// This is the actual middleware function to be executed.
+	m := func(handler Handler) Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			v, err := GetContextValues(ctx)
+			if err != nil {
+				return err
+			}
+
+			v.encoder = enc
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
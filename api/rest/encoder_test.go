@@ -0,0 +1,144 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// respondErrorHandler responds with the error response of a request error the
+// same way the Errors middleware does.
+func respondErrorHandler(err error) Handler {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		re := GetRequestError(err)
+		_ = SetIsError(ctx)
+		return Respond(ctx, w, re.Response(), re.StatusCode)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	notFound := NewRequestError(errors.New("site not found"), http.StatusNotFound, "not_found")
+	okHandler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return Respond(ctx, w, map[string]string{"name": "site"}, http.StatusOK)
+	}
+
+	shutdown := make(chan os.Signal, 1)
+	api := New(shutdown)
+	api.Handle(http.MethodGet, "/sites/{id}", respondErrorHandler(notFound))
+
+	public := api.Group("/public", UseEncoder(ProblemEncoder{TypeBaseURI: "https://example.com/problems/"}))
+	public.Handle(http.MethodGet, "/sites/{id}", respondErrorHandler(notFound))
+	public.Handle(http.MethodGet, "/sites", okHandler)
+
+	problemAPI := NewWithOptions(shutdown, WithEncoder(ProblemEncoder{}))
+	problemAPI.Handle(http.MethodGet, "/sites/{id}", respondErrorHandler(notFound))
+
+	t.Run("Envelope", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/sites/1", nil))
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected Content-Type 'application/json', got '%s'", ct)
+		}
+
+		var resp struct {
+			Success bool          `json:"success"`
+			Errors  ErrorResponse `json:"errors"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.Success || resp.Errors.Code != "not_found" {
+			t.Errorf("Unexpected response: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("Problem Group", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/public/sites/1?verbose=1", nil)
+		req.Header.Set(HeaderRequestID, "abc-123")
+		api.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status %v, got %v", http.StatusNotFound, rr.Code)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("Expected Content-Type 'application/problem+json', got '%s'", ct)
+		}
+
+		var p map[string]interface{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &p); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+
+		expected := map[string]interface{}{
+			"type":       "https://example.com/problems/not_found",
+			"title":      "Not Found",
+			"status":     float64(http.StatusNotFound),
+			"detail":     "site not found",
+			"instance":   "/public/sites/1?verbose=1",
+			"code":       "not_found",
+			"request_id": "abc-123",
+		}
+		if len(p) != len(expected) {
+			t.Errorf("Unexpected problem: %s", rr.Body.String())
+		}
+		for k, v := range expected {
+			if p[k] != v {
+				t.Errorf("Unexpected %s: got %v want %v", k, p[k], v)
+			}
+		}
+	})
+
+	t.Run("Problem Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/public/sites", nil))
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected Content-Type 'application/json', got '%s'", ct)
+		}
+		if body := rr.Body.String(); body != `{"name":"site"}` {
+			t.Errorf("Unexpected body: %s", body)
+		}
+	})
+
+	t.Run("Problem API", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		problemAPI.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/sites/1", nil))
+
+		var p Problem
+		if err := json.Unmarshal(rr.Body.Bytes(), &p); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if p.Type != "about:blank" || p.Status != http.StatusNotFound || p.Detail != "site not found" {
+			t.Errorf("Unexpected problem: %s", rr.Body.String())
+		}
+	})
+}
+
+func TestProblemEncoder_Fields(t *testing.T) {
+	ctx := context.WithValue(context.Background(), key, &ContextValues{IsError: true})
+	er := ErrorResponse{
+		Code:    CodeValidation,
+		Message: "validation failed",
+		Fields:  []FieldError{{Field: "name", Message: "name is required"}},
+	}
+
+	doc, _ := ProblemEncoder{}.Encode(ctx, er, http.StatusUnprocessableEntity)
+	b, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"code":"validation_failed","detail":"validation failed","fields":[{"field":"name","message":"name is required"}],"status":422,"title":"Unprocessable Entity","type":"about:blank"}`
+	if string(b) != expected {
+		t.Errorf("Unexpected problem: got %s want %s", b, expected)
+	}
+}
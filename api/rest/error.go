@@ -2,6 +2,7 @@ package rest
 
 import (
 	"errors"
+	"strings"
 )
 
 var (
@@ -13,3 +14,195 @@ var (
 	// from the context.
 	ErrMissingContext = errors.New("api value missing from context")
 )
+
+// Error codes used by the package for the machine-readable part of an
+// ErrorResponse.
+const (
+	// CodeInternal is the code used for any error which is not trusted.
+	CodeInternal = "internal_error"
+
+	// CodeInvalidParameter is the code used when a request parameter is
+	// missing or malformed.
+	CodeInvalidParameter = "invalid_parameter"
+
+	// CodeInvalidBody is the code used when the request body is malformed.
+	CodeInvalidBody = "invalid_body"
+
+	// CodeValidation is the code used when the request body fails the
+	// validation.
+	CodeValidation = "validation_failed"
+
+	// CodeUnsupportedMediaType is the code used when the request body is not
+	// sent in a supported format.
+	CodeUnsupportedMediaType = "unsupported_media_type"
+
+	// CodeRequestTooLarge is the code used when the request body exceeds the
+	// maximum size.
+	CodeRequestTooLarge = "request_too_large"
+
+	// CodeNotAcceptable is the code used when none of the media types
+	// accepted by the client is supported.
+	CodeNotAcceptable = "not_acceptable"
+
+	// CodeUnauthenticated is the code used when the request does not carry
+	// valid credentials.
+	CodeUnauthenticated = "unauthenticated"
+
+	// CodeForbidden is the code used when the authenticated principal is not
+	// allowed to perform the request.
+	CodeForbidden = "forbidden"
+
+	// CodeRateLimited is the code used when the client sent too many
+	// requests.
+	CodeRateLimited = "rate_limited"
+
+	// CodeTimeout is the code used when the request did not complete in
+	// time.
+	CodeTimeout = "timeout"
+
+	// CodeIdempotencyConflict is the code used when a request with the same
+	// idempotency key is still in progress.
+	CodeIdempotencyConflict = "idempotency_conflict"
+
+	// CodeIdempotencyMismatch is the code used when an idempotency key is
+	// reused for a different request.
+	CodeIdempotencyMismatch = "idempotency_mismatch"
+
+	// CodePreconditionFailed is the code used when a conditional request does
+	// not match the current state of the resource.
+	CodePreconditionFailed = "precondition_failed"
+)
+
+// ErrorResponse is the form used for API responses from failures in the API.
+type ErrorResponse struct {
+	// Code
+	//
+	// example: not_found
+	Code string `json:"code,omitempty" xml:"code,omitempty"`
+
+	// Message
+	//
+	// example: site not found
+	Message string `json:"message" xml:"message"`
+
+	// Fields
+	// in: body
+	Fields []FieldError `json:"fields,omitempty" xml:"fields>field,omitempty"`
+}
+
+// FieldError is used to indicate an error with a specific request field.
+type FieldError struct {
+	// Field
+	//
+	// example: name
+	Field string `json:"field" xml:"name"`
+
+	// Message
+	//
+	// example: name is required
+	Message string `json:"message" xml:"message"`
+}
+
+// FieldErrors represents a collection of field errors.
+type FieldErrors []FieldError
+
+// Error implements the error interface.
+func (fe FieldErrors) Error() string {
+	msgs := make([]string, 0, len(fe))
+	for _, f := range fe {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// RequestError is used to pass an error during the request through the
+// application with web specific context. It is a trusted error, the message
+// is safe to be sent back to the client.
+type RequestError struct {
+	// Err is the underlying error, its message is sent to the client.
+	Err error
+	// StatusCode is the HTTP status code to respond with.
+	StatusCode int
+	// Code is the machine-readable error code.
+	Code string
+}
+
+// NewRequestError wraps a provided error with an HTTP status code and a
+// machine-readable code. This function should be used when handlers encounter
+// expected errors.
+func NewRequestError(err error, statusCode int, code string) error {
+	return &RequestError{
+		Err:        err,
+		StatusCode: statusCode,
+		Code:       code,
+	}
+}
+
+// Error implements the error interface. It uses the default message of the
+// wrapped error. This is what will be shown in the services' logs.
+func (re *RequestError) Error() string {
+	return re.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (re *RequestError) Unwrap() error {
+	return re.Err
+}
+
+// Response returns the ErrorResponse to be sent back to the client. The field
+// errors wrapped by the request error are listed in the response.
+func (re *RequestError) Response() ErrorResponse {
+	er := ErrorResponse{
+		Code:    re.Code,
+		Message: re.Err.Error(),
+	}
+
+	var fe FieldErrors
+	if errors.As(re.Err, &fe) {
+		er.Fields = fe
+	}
+
+	return er
+}
+
+// IsRequestError checks if an error of type RequestError exists.
+func IsRequestError(err error) bool {
+	var re *RequestError
+	return errors.As(err, &re)
+}
+
+// GetRequestError returns a copy of the RequestError pointer.
+func GetRequestError(err error) *RequestError {
+	var re *RequestError
+	if !errors.As(err, &re) {
+		return nil
+	}
+
+	return re
+}
+
+// shutdownError is a type used to help with the graceful termination of the
+// service.
+type shutdownError struct {
+	Message string
+}
+
+// NewShutdownError returns an error that causes the framework to signal a
+// graceful shutdown. It should only be used when an integrity issue is
+// identified.
+func NewShutdownError(message string) error {
+	return &shutdownError{message}
+}
+
+// Error is the implementation of the error interface.
+func (se *shutdownError) Error() string {
+	return se.Message
+}
+
+// IsShutdown checks to see if the shutdown error is contained in the
+// specified error value.
+func IsShutdown(err error) bool {
+	var se *shutdownError
+	return errors.As(err, &se)
+}
@@ -0,0 +1,69 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestRequestError(t *testing.T) {
+	t.Run("Request Error", func(t *testing.T) {
+		base := errors.New("site not found")
+		err := NewRequestError(base, http.StatusNotFound, "not_found")
+
+		if !IsRequestError(err) {
+			t.Fatal("Expected a request error")
+		}
+		if !errors.Is(err, base) {
+			t.Error("Expected the request error to wrap the base error")
+		}
+		if err.Error() != "site not found" {
+			t.Errorf("Unexpected message: %q", err.Error())
+		}
+
+		re := GetRequestError(fmt.Errorf("wrapped: %w", err))
+		if re == nil {
+			t.Fatal("Expected to get the wrapped request error")
+		}
+		if re.StatusCode != http.StatusNotFound || re.Code != "not_found" {
+			t.Errorf("Unexpected values: %+v", re)
+		}
+
+		er := re.Response()
+		if er.Code != "not_found" || er.Message != "site not found" {
+			t.Errorf("Unexpected error response: %+v", er)
+		}
+	})
+
+	t.Run("Untrusted Error", func(t *testing.T) {
+		err := errors.New("connection refused")
+
+		if IsRequestError(err) {
+			t.Error("Expected not to be a request error")
+		}
+		if GetRequestError(err) != nil {
+			t.Error("Expected nil request error")
+		}
+	})
+}
+
+func TestShutdownError(t *testing.T) {
+	err := NewShutdownError("integrity issue")
+
+	if !IsShutdown(err) {
+		t.Error("Expected a shutdown error")
+	}
+	if !IsShutdown(fmt.Errorf("wrapped: %w", err)) {
+		t.Error("Expected a wrapped shutdown error")
+	}
+	if IsShutdown(errors.New("integrity issue")) {
+		t.Error("Expected not to be a shutdown error")
+	}
+	if err.Error() != "integrity issue" {
+		t.Errorf("Unexpected message: %q", err.Error())
+	}
+}
@@ -0,0 +1,239 @@
+package rest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrPreconditionFailed is the error sent when a conditional request does not
+// match the current state of the resource.
+var ErrPreconditionFailed = errors.New("the resource does not match the precondition")
+
+// ETagMode is the kind of ETags computed by Respond.
+type ETagMode int
+
+const (
+	// NoETags disables the ETags.
+	NoETags ETagMode = iota
+	// StrongETags tags the responses with strong ETags, usable by the
+	// conditional reads and by the If-Match preconditions of the updates.
+	StrongETags
+	// WeakETags tags the responses with weak ETags, usable by the conditional
+	// reads only as If-Match requires a strong ETag.
+	WeakETags
+)
+
+// UseETags selects the kind of ETags computed by Respond for the requests
+// going through this middleware. It is meant to select the ETags of a route
+// group or a single route.
+func UseETags(mode ETagMode) Middleware {
+	// This is the actual middleware function to be executed.
+	m := func(handler Handler) Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			v, err := GetContextValues(ctx)
+			if err != nil {
+				return err
+			}
+
+			v.etags = mode
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// ComputeETag returns the ETag Respond sends for the data with the format
+// negotiated for the request, e.g. to check the preconditions of an update
+// against the current state of the resource. It returns a strong ETag when
+// the ETags are disabled.
+func ComputeETag(ctx context.Context, data interface{}) (string, error) {
+	v, err := GetContextValues(ctx)
+	if err != nil {
+		return "", ErrMissingContext
+	}
+
+	_, codec, _ := negotiateCodec(v)
+
+	return computeETag(v, codec, data)
+}
+
+// CheckPreconditions evaluates the If-Match, If-Unmodified-Since and, for the
+// unsafe methods, If-None-Match headers of the request against the current
+// state of the resource. It returns a RequestError with the status code 412
+// when they are not met, so the update is not applied.
+//
+// An empty etag means the resource does not exist, e.g. for a creation
+// guarded by "If-None-Match: *". A zero lastModified ignores the dates. The
+// ETags sent by the client may be the ones of an encoded representation, see
+// EncodedETag. The conditional reads are answered by Respond and are not
+// reported here.
+func CheckPreconditions(r *http.Request, etag string, lastModified time.Time) error {
+	if evaluatePreconditions(r, etag, lastModified) == http.StatusPreconditionFailed {
+		return NewRequestError(ErrPreconditionFailed, http.StatusPreconditionFailed, CodePreconditionFailed)
+	}
+
+	return nil
+}
+
+// EncodedETag returns the ETag of the representation encoded with the content
+// coding, e.g. "<tag>-gzip" for a response compressed with gzip. The ETag is
+// kept strong so it can still be sent with If-Match, and Respond and
+// CheckPreconditions match it against the ETag of the data. A weak ETag is
+// returned as is, as the encoded representation is semantically the same.
+func EncodedETag(etag string, encoding string) string {
+	if encoding == "" || strings.HasPrefix(etag, "W/") || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// computeETag returns the ETag of the data once encoded with the codec. The
+// ETag is computed from the data and the pagination rather than from the
+// whole document, as the timestamp and the request ID of the envelope change
+// on every response.
+func computeETag(v *ContextValues, codec Codec, data interface{}) (string, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("marshal fail: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte(codec.MediaType()))
+	h.Write([]byte{0})
+	h.Write(b)
+
+	if v.pagination != nil {
+		pb, err := json.Marshal(v.pagination)
+		if err != nil {
+			return "", fmt.Errorf("marshal fail: %w", err)
+		}
+		h.Write(pb)
+	}
+
+	etag := `"` + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:18]) + `"`
+	if v.etags == WeakETags {
+		etag = "W/" + etag
+	}
+
+	return etag, nil
+}
+
+// evaluatePreconditions evaluates the conditional headers of the request in
+// the order of RFC 9110 section 13.2.2. It returns http.StatusNotModified or
+// http.StatusPreconditionFailed when the request must not be served as is,
+// and 0 otherwise.
+func evaluatePreconditions(r *http.Request, etag string, lastModified time.Time) int {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	lastModified = lastModified.Truncate(time.Second)
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && lastModified.After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if matchETag(inm, etag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && safe && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+// matchETag tells whether the ETag matches one of the ETags of a conditional
+// header value, using the weak or the strong comparison. The ETags of the
+// encoded representations match the ETag they are derived from. An empty
+// ETag, of a resource which does not exist, matches nothing.
+func matchETag(header string, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		switch {
+		case candidate == "*":
+			return true
+		case weak:
+			if sameETag(strings.TrimPrefix(candidate, "W/"), strings.TrimPrefix(etag, "W/")) {
+				return true
+			}
+		case !strings.HasPrefix(candidate, "W/") && sameETag(candidate, etag):
+			return true
+		}
+	}
+
+	return false
+}
+
+// sameETag tells whether the candidate is the ETag or the ETag of one of its
+// encoded representations, see EncodedETag.
+func sameETag(candidate string, etag string) bool {
+	if candidate == etag {
+		return true
+	}
+
+	opaque, ok := strings.CutSuffix(etag, `"`)
+	if !ok {
+		return false
+	}
+
+	encoding, ok := strings.CutPrefix(candidate, opaque+"-")
+	if !ok {
+		return false
+	}
+
+	encoding, ok = strings.CutSuffix(encoding, `"`)
+	if !ok || encoding == "" {
+		return false
+	}
+
+	for _, c := range encoding {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// notModified answers a conditional read with the status code 304. The
+// headers of the response are kept as they describe the representation held
+// by the client.
+func notModified(ctx context.Context, w http.ResponseWriter) error {
+	if err := SetStatusCode(ctx, http.StatusNotModified); err != nil {
+		return err
+	}
+
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+
+	return nil
+}
@@ -0,0 +1,172 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRespondETag(t *testing.T) {
+	modified := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+
+	// The errors are reported by the middleware, which are not used here.
+	var handlerErr error
+	api := NewWithOptions(make(chan os.Signal, 1),
+		WithETags(StrongETags),
+		WithErrorHandler(func(ctx context.Context, r *http.Request, err error) {
+			handlerErr = err
+		}),
+	)
+	api.Handle(http.MethodGet, "/sites/1", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		return Respond(ctx, w, map[string]string{"name": "site"}, http.StatusOK)
+	})
+
+	serve := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/sites/1", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rr := httptest.NewRecorder()
+		handlerErr = nil
+		api.ServeHTTP(rr, req)
+		if re := GetRequestError(handlerErr); re != nil {
+			rr.Code = re.StatusCode
+		}
+		return rr
+	}
+
+	etag := serve("", "").Header().Get("ETag")
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("Expected a strong ETag, got %q", etag)
+	}
+
+	testCases := []struct {
+		name           string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{name: "Unconditional", expectedStatus: http.StatusOK},
+		{name: "If-None-Match Matching", header: "If-None-Match", value: `"other", ` + etag, expectedStatus: http.StatusNotModified},
+		{name: "If-None-Match Weak", header: "If-None-Match", value: "W/" + etag, expectedStatus: http.StatusNotModified},
+		{name: "If-None-Match Any", header: "If-None-Match", value: "*", expectedStatus: http.StatusNotModified},
+		{name: "If-None-Match Changed", header: "If-None-Match", value: `"other"`, expectedStatus: http.StatusOK},
+		{name: "If-Modified-Since Not Modified", header: "If-Modified-Since", value: modified.Format(http.TimeFormat), expectedStatus: http.StatusNotModified},
+		{name: "If-Modified-Since Modified", header: "If-Modified-Since", value: modified.Add(-time.Hour).Format(http.TimeFormat), expectedStatus: http.StatusOK},
+		{name: "If-Match Matching", header: "If-Match", value: etag, expectedStatus: http.StatusOK},
+		{name: "If-Match Changed", header: "If-Match", value: `"other"`, expectedStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := serve(tc.header, tc.value)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tc.expectedStatus)
+			}
+
+			switch rr.Code {
+			case http.StatusOK:
+				if rr.Header().Get("ETag") != etag {
+					t.Errorf("Expected the ETag to be stable, got %q want %q", rr.Header().Get("ETag"), etag)
+				}
+			case http.StatusNotModified:
+				if rr.Body.Len() != 0 || rr.Header().Get("ETag") != etag {
+					t.Errorf("Expected an empty 304 with the ETag, got %q %v", rr.Body.String(), rr.Header())
+				}
+			}
+		})
+	}
+}
+
+func TestRespondETagModes(t *testing.T) {
+	api := New(make(chan os.Signal, 1))
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return Respond(ctx, w, "site", http.StatusOK)
+	}
+	api.Handle(http.MethodGet, "/none", handler)
+	api.Handle(http.MethodGet, "/weak", handler, UseETags(WeakETags))
+	api.Handle(http.MethodGet, "/custom", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("ETag", `"v42"`)
+		return Respond(ctx, w, "site", http.StatusOK)
+	}, UseETags(StrongETags))
+	api.Handle(http.MethodGet, "/accepted", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return Respond(ctx, w, "site", http.StatusAccepted)
+	}, UseETags(StrongETags))
+
+	testCases := []struct {
+		path     string
+		expected func(etag string) bool
+	}{
+		{path: "/none", expected: func(etag string) bool { return etag == "" }},
+		{path: "/weak", expected: func(etag string) bool { return strings.HasPrefix(etag, `W/"`) }},
+		{path: "/custom", expected: func(etag string) bool { return etag == `"v42"` }},
+		{path: "/accepted", expected: func(etag string) bool { return etag == "" }},
+	}
+
+	for _, tc := range testCases {
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+		if etag := rr.Header().Get("ETag"); !tc.expected(etag) {
+			t.Errorf("%s: unexpected ETag %q", tc.path, etag)
+		}
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	modified := time.Date(2024, 5, 15, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name         string
+		header       string
+		value        string
+		etag         string
+		lastModified time.Time
+		wantErr      bool
+	}{
+		{name: "Unconditional", etag: `"v1"`},
+		{name: "If-Match Matching", header: "If-Match", value: `"v1"`, etag: `"v1"`},
+		{name: "If-Match Changed", header: "If-Match", value: `"v1"`, etag: `"v2"`, wantErr: true},
+		{name: "If-Match Weak", header: "If-Match", value: `W/"v1"`, etag: `W/"v1"`, wantErr: true},
+		{name: "If-Match Encoded", header: "If-Match", value: `"v1-gzip"`, etag: `"v1"`},
+		{name: "If-Match Encoded Changed", header: "If-Match", value: `"v1-gzip"`, etag: `"v2"`, wantErr: true},
+		{name: "If-Match Suffixed", header: "If-Match", value: `"v1-Gzip"`, etag: `"v1"`, wantErr: true},
+		{name: "If-Match Any", header: "If-Match", value: "*", etag: `"v1"`},
+		{name: "If-Match Any Missing", header: "If-Match", value: "*", wantErr: true},
+		{name: "If-None-Match Any Missing", header: "If-None-Match", value: "*"},
+		{name: "If-None-Match Any Existing", header: "If-None-Match", value: "*", etag: `"v1"`, wantErr: true},
+		{name: "If-Unmodified-Since Unchanged", header: "If-Unmodified-Since", value: modified.Format(http.TimeFormat), lastModified: modified},
+		{name: "If-Unmodified-Since Changed", header: "If-Unmodified-Since", value: modified.Format(http.TimeFormat), lastModified: modified.Add(time.Minute), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/sites/1", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+
+			err := CheckPreconditions(req, tc.etag, tc.lastModified)
+			if !tc.wantErr {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			re := GetRequestError(err)
+			if re == nil || re.StatusCode != http.StatusPreconditionFailed || re.Code != CodePreconditionFailed {
+				t.Errorf("Expected a precondition failed error, got %v", err)
+			}
+		})
+	}
+}
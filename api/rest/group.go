@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"strings"
+)
+
+// Group is a set of routes sharing a path prefix and middleware. It registers
+// its routes on the mux of the API it was created from.
+type Group struct {
+	api    *API
+	prefix string
+	mw     []Middleware
+}
+
+// Group creates a route group for the given path prefix. The group middleware
+// is executed after the API's general middleware and before the route
+// specific middleware.
+//
+//	prefix: path prefix of every route of the group, e.g. "/v1"
+//	mw: list of middleware to execute on each request of the group
+func (a *API) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		api:    a,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		mw:     mw,
+	}
+}
+
+// Group creates a nested route group. The prefix is appended to the prefix of
+// the parent group and the middleware is executed after the parent's
+// middleware.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	groupMW := make([]Middleware, 0, len(g.mw)+len(mw))
+	groupMW = append(groupMW, g.mw...)
+	groupMW = append(groupMW, mw...)
+
+	return &Group{
+		api:    g.api,
+		prefix: g.prefix + strings.TrimSuffix(prefix, "/"),
+		mw:     groupMW,
+	}
+}
+
+// Handle sets a handler function for a given HTTP method and path pair, the
+// path being relative to the group prefix.
+func (g *Group) Handle(method string, path string, handler Handler, mw ...Middleware) {
+	g.api.handle(method, g.prefix+path, Operation{}, handler, g.mw, mw)
+}
+
+// HandleDoc sets a handler function for a given HTTP method and path pair,
+// like Handle, and documents it in the OpenAPI document with the operation.
+func (g *Group) HandleDoc(method string, path string, op Operation, handler Handler, mw ...Middleware) {
+	g.api.handle(method, g.prefix+path, op, handler, g.mw, mw)
+}
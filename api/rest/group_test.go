@@ -0,0 +1,96 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// traceMiddleware returns a middleware writing its name before calling the
+// next handler.
+func traceMiddleware(name string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Write([]byte(name + " "))
+			return next(ctx, w, r)
+		}
+	}
+}
+
+// pathHandler writes the registered path from the context values.
+func pathHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := GetContextValues(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.Write([]byte(v.Path))
+	return nil
+}
+
+func TestGroup(t *testing.T) {
+	shutdown := make(chan os.Signal, 1)
+	api := New(shutdown, traceMiddleware("global"))
+
+	v1 := api.Group("/v1/", traceMiddleware("v1"))
+	v1.Handle(http.MethodGet, "/sites/{id}", pathHandler, traceMiddleware("route"))
+
+	admin := v1.Group("/admin", traceMiddleware("admin"))
+	admin.Handle(http.MethodDelete, "/sites/{id}", pathHandler)
+
+	api.Handle(http.MethodGet, "/health", pathHandler)
+
+	testCases := []struct {
+		name     string
+		method   string
+		target   string
+		expected string
+	}{
+		{
+			name:     "Group Route",
+			method:   http.MethodGet,
+			target:   "/v1/sites/1",
+			expected: "global v1 route /v1/sites/{id}",
+		},
+		{
+			name:     "Nested Group Route",
+			method:   http.MethodDelete,
+			target:   "/v1/admin/sites/1",
+			expected: "global v1 admin /v1/admin/sites/{id}",
+		},
+		{
+			name:     "API Route",
+			method:   http.MethodGet,
+			target:   "/health",
+			expected: "global /health",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.target, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status %v, got %v", http.StatusOK, rr.Code)
+			}
+			if body := rr.Body.String(); body != tc.expected {
+				t.Errorf("Unexpected body: got %q, want %q", body, tc.expected)
+			}
+		})
+	}
+
+	t.Run("Unprefixed Route", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/sites/1", nil))
+
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("Expected status %v, got %v", http.StatusNotFound, rr.Code)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	// Standard library packages
+	"crypto/sha256"
+	"net/http"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// HeaderAPIKey is the default header carrying the API key.
+const HeaderAPIKey = "X-API-Key"
+
+// APIKeyVerifier authenticates the requests carrying a static API key.
+type APIKeyVerifier struct {
+	header string
+	keys   map[[sha256.Size]byte]rest.Principal
+}
+
+// NewAPIKeyVerifier creates a Verifier for the given API keys, keyed by the
+// key value. The key is read from the given header, HeaderAPIKey when empty.
+func NewAPIKeyVerifier(header string, keys map[string]rest.Principal) *APIKeyVerifier {
+	if header == "" {
+		header = HeaderAPIKey
+	}
+
+	v := &APIKeyVerifier{
+		header: header,
+		keys:   make(map[[sha256.Size]byte]rest.Principal, len(keys)),
+	}
+
+	// The keys are looked up by hash so the lookup time does not depend on
+	// the content of the keys.
+	for key, p := range keys {
+		p.Method = "api_key"
+		v.keys[sha256.Sum256([]byte(key))] = p
+	}
+
+	return v
+}
+
+// Verify implements the Verifier interface.
+func (v *APIKeyVerifier) Verify(r *http.Request) (*rest.Principal, error) {
+	key := r.Header.Get(v.header)
+	if key == "" {
+		return nil, ErrNoCredentials
+	}
+
+	p, ok := v.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &p, nil
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+var (
+	// ErrNoCredentials is returned by a Verifier when the request does not
+	// carry the kind of credentials it verifies.
+	ErrNoCredentials = errors.New("authentication required")
+
+	// ErrInvalidCredentials is returned by a Verifier when the credentials of
+	// the request are not valid.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Verifier authenticates the requests carrying a kind of credentials.
+type Verifier interface {
+	// Verify returns the principal the request is authenticated as. It
+	// returns ErrNoCredentials when the request does not carry its kind of
+	// credentials, any other error meaning the credentials are not valid.
+	Verify(r *http.Request) (*rest.Principal, error)
+}
+
+// VerifierFunc is an adapter to use a function as a Verifier.
+type VerifierFunc func(r *http.Request) (*rest.Principal, error)
+
+// Verify implements the Verifier interface.
+func (f VerifierFunc) Verify(r *http.Request) (*rest.Principal, error) {
+	return f(r)
+}
+
+// Challenger is implemented by the verifiers which announce their
+// authentication scheme in the WWW-Authenticate header of the 401 responses.
+type Challenger interface {
+	// Challenge returns the challenge of the scheme, e.g. "Bearer".
+	Challenge() string
+}
+
+// Authenticate requires the requests to be authenticated by one of the
+// verifiers, tried in order until one finds its kind of credentials. The
+// principal is set in the context values, see rest.GetPrincipal. A request
+// without valid credentials fails with a 401 RequestError, whose message does
+// not tell why the credentials were rejected.
+func Authenticate(verifiers ...Verifier) rest.Middleware {
+	var challenges []string
+	for _, v := range verifiers {
+		if c, ok := v.(Challenger); ok {
+			challenges = append(challenges, c.Challenge())
+		}
+	}
+	challenge := strings.Join(challenges, ", ")
+
+	// This is the actual middleware function to be executed.
+	m := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			authErr := ErrNoCredentials
+
+			for _, v := range verifiers {
+				p, err := v.Verify(r)
+				if errors.Is(err, ErrNoCredentials) {
+					continue
+				}
+
+				if err != nil || p == nil {
+					authErr = ErrInvalidCredentials
+					break
+				}
+
+				if err := rest.SetPrincipal(ctx, p); err != nil {
+					return err
+				}
+
+				return handler(ctx, w, r)
+			}
+
+			if challenge != "" {
+				w.Header().Set("WWW-Authenticate", challenge)
+			}
+
+			return rest.NewRequestError(authErr, http.StatusUnauthorized, rest.CodeUnauthenticated)
+		})
+
+		return h
+	}
+
+	return m
+}
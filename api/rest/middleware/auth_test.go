@@ -0,0 +1,181 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// newTestAuthAPI returns an API whose route echoes the authenticated subject
+// and the request body.
+func newTestAuthAPI(verifiers ...Verifier) *rest.API {
+	api := rest.New(make(chan os.Signal, 1), Errors())
+	api.Handle(http.MethodPost, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		p, ok := rest.GetPrincipal(ctx)
+		if !ok {
+			return rest.Respond(ctx, w, nil, http.StatusInternalServerError)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+
+		return rest.Respond(ctx, w, map[string]string{
+			"subject": p.Subject,
+			"method":  p.Method,
+			"body":    string(body),
+		}, http.StatusOK)
+	}, Authenticate(verifiers...))
+
+	return api
+}
+
+// authResult is the response of the test API.
+type authResult struct {
+	Success bool               `json:"success"`
+	Data    map[string]string  `json:"data"`
+	Errors  rest.ErrorResponse `json:"errors"`
+}
+
+func serveAuth(t *testing.T, api *rest.API, req *http.Request) (*httptest.ResponseRecorder, authResult) {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	var res authResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Failed to unmarshal the response: %v", err)
+	}
+
+	return rr, res
+}
+
+func TestAuthenticate(t *testing.T) {
+	api := newTestAuthAPI(
+		NewAPIKeyVerifier("", map[string]rest.Principal{"key-1": {Subject: "service-a"}}),
+		NewJWTVerifier(HMACSecret([]byte("secret"))),
+	)
+
+	t.Run("Authenticated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/sites", nil)
+		req.Header.Set(HeaderAPIKey, "key-1")
+
+		rr, res := serveAuth(t, api, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if res.Data["subject"] != "service-a" || res.Data["method"] != "api_key" {
+			t.Errorf("Expected the principal in the context, got %v", res.Data)
+		}
+	})
+
+	testCases := []struct {
+		name    string
+		header  string
+		value   string
+		message string
+	}{
+		{name: "Missing Credentials", message: ErrNoCredentials.Error()},
+		{name: "Invalid API Key", header: HeaderAPIKey, value: "key-2", message: ErrInvalidCredentials.Error()},
+		{name: "Invalid Token", header: "Authorization", value: "Bearer abc.def.ghi", message: ErrInvalidCredentials.Error()},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/sites", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+
+			rr, res := serveAuth(t, api, req)
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+			}
+			if res.Success || res.Errors.Code != rest.CodeUnauthenticated || res.Errors.Message != tc.message {
+				t.Errorf("Expected an unauthenticated error, got %+v", res.Errors)
+			}
+			if got := rr.Header().Get("WWW-Authenticate"); got != "Bearer" {
+				t.Errorf("Expected WWW-Authenticate %q, got %q", "Bearer", got)
+			}
+		})
+	}
+}
+
+func TestHMACVerifier(t *testing.T) {
+	secret := []byte("shared-secret")
+	api := newTestAuthAPI(NewHMACVerifier(map[string]HMACKey{
+		"key-1": {Secret: secret, Principal: rest.Principal{Subject: "partner"}},
+	}))
+
+	newRequest := func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/sites?dry_run=true", bytes.NewBufferString(`{"name":"site"}`))
+	}
+
+	t.Run("Signed", func(t *testing.T) {
+		req := newRequest()
+		if err := SignRequest(req, "key-1", secret); err != nil {
+			t.Fatalf("SignRequest failed: %v", err)
+		}
+
+		rr, res := serveAuth(t, api, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if res.Data["subject"] != "partner" || res.Data["method"] != "hmac" {
+			t.Errorf("Expected the principal in the context, got %v", res.Data)
+		}
+		if res.Data["body"] != `{"name":"site"}` {
+			t.Errorf("Expected the body to be restored, got %q", res.Data["body"])
+		}
+	})
+
+	testCases := []struct {
+		name   string
+		tamper func(req *http.Request)
+	}{
+		{name: "Wrong Secret", tamper: func(req *http.Request) {
+			_ = SignRequest(req, "key-1", []byte("other"))
+		}},
+		{name: "Unknown Key", tamper: func(req *http.Request) {
+			_ = SignRequest(req, "key-2", secret)
+		}},
+		{name: "Tampered Body", tamper: func(req *http.Request) {
+			_ = SignRequest(req, "key-1", secret)
+			req.Body = io.NopCloser(bytes.NewBufferString(`{"name":"evil"}`))
+		}},
+		{name: "Tampered Query", tamper: func(req *http.Request) {
+			_ = SignRequest(req, "key-1", secret)
+			req.URL.RawQuery = "dry_run=false"
+		}},
+		{name: "Expired", tamper: func(req *http.Request) {
+			_ = SignRequest(req, "key-1", secret)
+			req.Header.Set(HeaderSignatureTimestamp, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newRequest()
+			tc.tamper(req)
+
+			rr, _ := serveAuth(t, api, req)
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+			}
+			if got := rr.Header().Get("WWW-Authenticate"); got != HMACScheme {
+				t.Errorf("Expected WWW-Authenticate %q, got %q", HMACScheme, got)
+			}
+		})
+	}
+}
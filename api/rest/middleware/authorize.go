@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+	"github.com/coderkakarrot/go-pkg-lib/metric"
+)
+
+// ErrForbidden is the error returned when the principal is not allowed to
+// perform the request.
+var ErrForbidden = errors.New("permission denied")
+
+// Policy decides whether a principal can perform a request, e.g. by checking
+// the ownership of the resource identified by the path parameters.
+type Policy interface {
+	// Allow reports whether the principal can perform the request. An error
+	// is returned as is, e.g. a RequestError for a missing resource.
+	Allow(ctx context.Context, p *rest.Principal, r *http.Request) (bool, error)
+}
+
+// PolicyFunc is an adapter to use a function as a Policy.
+type PolicyFunc func(ctx context.Context, p *rest.Principal, r *http.Request) (bool, error)
+
+// Allow implements the Policy interface.
+func (f PolicyFunc) Allow(ctx context.Context, p *rest.Principal, r *http.Request) (bool, error) {
+	return f(ctx, p, r)
+}
+
+// Authorizer guards the routes with the permissions of the principal set by
+// Authenticate. A request without principal fails with a 401 RequestError and
+// a denied request fails with a 403 RequestError whose code is
+// rest.CodeForbidden.
+type Authorizer struct {
+	decisions *metric.Counter
+}
+
+// NewAuthorizer creates an Authorizer. The counter is optional, when it is not
+// nil it is incremented on every decision with the method, path, check and
+// decision ("allow" or "deny") attributes.
+func NewAuthorizer(decisions *metric.Counter) *Authorizer {
+	return &Authorizer{
+		decisions: decisions,
+	}
+}
+
+// defaultAuthorizer is the Authorizer used by the package functions.
+var defaultAuthorizer = NewAuthorizer(nil)
+
+// RequireScopes requires the principal to be granted all the scopes. See
+// Authorizer.RequireScopes to count the decisions.
+func RequireScopes(scopes ...string) rest.Middleware {
+	return defaultAuthorizer.RequireScopes(scopes...)
+}
+
+// RequireRoles requires the principal to have one of the roles. See
+// Authorizer.RequireRoles to count the decisions.
+func RequireRoles(roles ...string) rest.Middleware {
+	return defaultAuthorizer.RequireRoles(roles...)
+}
+
+// Authorize requires the policy to allow the request. See
+// Authorizer.Authorize to count the decisions.
+func Authorize(policy Policy) rest.Middleware {
+	return defaultAuthorizer.Authorize(policy)
+}
+
+// RequireScopes requires the principal to be granted all the scopes.
+func (a *Authorizer) RequireScopes(scopes ...string) rest.Middleware {
+	return a.guard("scope", func(_ context.Context, p *rest.Principal, _ *http.Request) error {
+		for _, scope := range scopes {
+			if !p.HasScope(scope) {
+				return fmt.Errorf("%w: missing scope %q", ErrForbidden, scope)
+			}
+		}
+
+		return nil
+	})
+}
+
+// RequireRoles requires the principal to have one of the roles.
+func (a *Authorizer) RequireRoles(roles ...string) rest.Middleware {
+	return a.guard("role", func(_ context.Context, p *rest.Principal, _ *http.Request) error {
+		for _, role := range roles {
+			if p.HasRole(role) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("%w: one of the roles %s is required", ErrForbidden, strings.Join(roles, ", "))
+	})
+}
+
+// Authorize requires the policy to allow the request.
+func (a *Authorizer) Authorize(policy Policy) rest.Middleware {
+	return a.guard("policy", func(ctx context.Context, p *rest.Principal, r *http.Request) error {
+		ok, err := policy.Allow(ctx, p, r)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return ErrForbidden
+		}
+
+		return nil
+	})
+}
+
+// guard returns the middleware running the check before the handler. The
+// check returns an error wrapping ErrForbidden to deny the request.
+func (a *Authorizer) guard(check string, allow func(ctx context.Context, p *rest.Principal, r *http.Request) error) rest.Middleware {
+	// This is the actual middleware function to be executed.
+	m := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			p, ok := rest.GetPrincipal(ctx)
+			if !ok {
+				a.count(ctx, r, check, false)
+				return rest.NewRequestError(ErrNoCredentials, http.StatusUnauthorized, rest.CodeUnauthenticated)
+			}
+
+			if err := allow(ctx, p, r); err != nil {
+				if !errors.Is(err, ErrForbidden) {
+					return err
+				}
+
+				a.count(ctx, r, check, false)
+				return rest.NewRequestError(err, http.StatusForbidden, rest.CodeForbidden)
+			}
+
+			a.count(ctx, r, check, true)
+
+			return handler(ctx, w, r)
+		})
+
+		return h
+	}
+
+	return m
+}
+
+// count records the decision in the counter, if any.
+func (a *Authorizer) count(ctx context.Context, r *http.Request, check string, allowed bool) {
+	if a.decisions == nil {
+		return
+	}
+
+	path := r.URL.Path
+	if v, err := rest.GetContextValues(ctx); err == nil {
+		path = v.Path
+	}
+
+	decision := "deny"
+	if allowed {
+		decision = "allow"
+	}
+
+	a.decisions.Add(ctx, 1, metric.Attributes{
+		"method":   r.Method,
+		"path":     path,
+		"check":    check,
+		"decision": decision,
+	})
+}
@@ -0,0 +1,167 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+	"github.com/coderkakarrot/go-pkg-lib/metric"
+)
+
+// withPrincipal authenticates every request as the principal, if any.
+func withPrincipal(p *rest.Principal) rest.Middleware {
+	return Authenticate(VerifierFunc(func(r *http.Request) (*rest.Principal, error) {
+		if p == nil {
+			return nil, ErrNoCredentials
+		}
+		return p, nil
+	}))
+}
+
+func TestAuthorize(t *testing.T) {
+	// The site is owned by its ID, e.g. user-1 owns site 1.
+	owner := PolicyFunc(func(ctx context.Context, p *rest.Principal, r *http.Request) (bool, error) {
+		id := rest.PathParam(r, "id")
+		if id == "404" {
+			return false, rest.NewRequestError(errors.New("site not found"), http.StatusNotFound, "not_found")
+		}
+		return p.Subject == "user-"+id, nil
+	})
+
+	testCases := []struct {
+		name      string
+		principal *rest.Principal
+		mw        rest.Middleware
+		target    string
+		status    int
+		code      string
+	}{
+		{
+			name:      "Scopes Granted",
+			principal: &rest.Principal{Scopes: []string{"sites:read", "sites:write"}},
+			mw:        RequireScopes("sites:read", "sites:write"),
+			target:    "/sites/1",
+			status:    http.StatusOK,
+		},
+		{
+			name:      "Scope Missing",
+			principal: &rest.Principal{Scopes: []string{"sites:read"}},
+			mw:        RequireScopes("sites:read", "sites:write"),
+			target:    "/sites/1",
+			status:    http.StatusForbidden,
+			code:      rest.CodeForbidden,
+		},
+		{
+			name:      "Role Granted",
+			principal: &rest.Principal{Roles: []string{"support"}},
+			mw:        RequireRoles("admin", "support"),
+			target:    "/sites/1",
+			status:    http.StatusOK,
+		},
+		{
+			name:      "Role Missing",
+			principal: &rest.Principal{Roles: []string{"viewer"}},
+			mw:        RequireRoles("admin", "support"),
+			target:    "/sites/1",
+			status:    http.StatusForbidden,
+			code:      rest.CodeForbidden,
+		},
+		{
+			name:      "Policy Allowed",
+			principal: &rest.Principal{Subject: "user-1"},
+			mw:        Authorize(owner),
+			target:    "/sites/1",
+			status:    http.StatusOK,
+		},
+		{
+			name:      "Policy Denied",
+			principal: &rest.Principal{Subject: "user-1"},
+			mw:        Authorize(owner),
+			target:    "/sites/2",
+			status:    http.StatusForbidden,
+			code:      rest.CodeForbidden,
+		},
+		{
+			name:      "Policy Error",
+			principal: &rest.Principal{Subject: "user-1"},
+			mw:        Authorize(owner),
+			target:    "/sites/404",
+			status:    http.StatusNotFound,
+			code:      "not_found",
+		},
+		{
+			name:   "Unauthenticated",
+			mw:     RequireScopes("sites:read"),
+			target: "/sites/1",
+			status: http.StatusUnauthorized,
+			code:   rest.CodeUnauthenticated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := rest.New(make(chan os.Signal, 1), Errors())
+
+			// The guard of an anonymous route sees no principal.
+			mw := []rest.Middleware{tc.mw}
+			if tc.principal != nil {
+				mw = []rest.Middleware{withPrincipal(tc.principal), tc.mw}
+			}
+
+			api.Handle(http.MethodDelete, "/sites/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return rest.Respond(ctx, w, map[string]string{"id": rest.PathParam(r, "id")}, http.StatusOK)
+			}, mw...)
+
+			rr, res := serveAuth(t, api, httptest.NewRequest(http.MethodDelete, tc.target, nil))
+			if rr.Code != tc.status {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tc.status)
+			}
+			if res.Errors.Code != tc.code {
+				t.Errorf("Expected error code %q, got %q", tc.code, res.Errors.Code)
+			}
+		})
+	}
+}
+
+func TestAuthorizerDecisions(t *testing.T) {
+	m, err := metric.Initialise("testAuthorizer")
+	if err != nil {
+		t.Fatalf("failed to initialize provider: %v", err)
+	}
+	defer m.Shutdown(context.Background())
+
+	decisions, err := m.NewCounter("authorization", "Incremental counter of the authorization decisions")
+	if err != nil {
+		t.Fatalf("failed to create the counter: %v", err)
+	}
+
+	authz := NewAuthorizer(decisions)
+	api := rest.New(make(chan os.Signal, 1), Errors())
+	api.Handle(http.MethodDelete, "/sites/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, "deleted", http.StatusOK)
+	}, withPrincipal(&rest.Principal{Scopes: []string{"sites:write"}}), authz.RequireScopes("sites:write"), authz.RequireRoles("admin"))
+
+	api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/sites/1", nil))
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+
+	expected := []string{
+		`authorization_total{check="scope",decision="allow",method="DELETE",otel_scope_name="testAuthorizer",otel_scope_version="",path="/sites/{id}"} 1`,
+		`authorization_total{check="role",decision="deny",method="DELETE",otel_scope_name="testAuthorizer",otel_scope_version="",path="/sites/{id}"} 1`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(body, e) {
+			t.Errorf("Expected metric %q in:\n%s", e, body)
+		}
+	}
+}
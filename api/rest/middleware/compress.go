@@ -0,0 +1,480 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	// Third party packages
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// The content codings supported by the Compress middleware.
+const (
+	EncodingZstd   = "zstd"
+	EncodingBrotli = "br"
+	EncodingGzip   = "gzip"
+)
+
+// defaultMinCompressSize is the default size under which the responses are
+// not compressed, as the saving does not pay for the cost.
+const defaultMinCompressSize = 1024
+
+// CompressOption configures the Compress middleware.
+type CompressOption interface {
+	apply(*compressOptions)
+}
+
+// compressOptions holds the settings of the Compress middleware.
+type compressOptions struct {
+	// encodings holds the supported content codings by order of preference.
+	encodings []string
+	// minSize is the size under which the responses are not compressed.
+	minSize int
+	// types holds the compressible media types, e.g. application/json or
+	// text/*.
+	types []string
+}
+
+type compressOptionFunc func(*compressOptions)
+
+func (f compressOptionFunc) apply(o *compressOptions) { f(o) }
+
+// WithEncodings sets the supported content codings by order of preference,
+// used when the client accepts several of them equally. The default order is
+// zstd, br then gzip. Unknown codings are ignored.
+func WithEncodings(encodings ...string) CompressOption {
+	return compressOptionFunc(func(o *compressOptions) {
+		o.encodings = o.encodings[:0]
+		for _, e := range encodings {
+			if _, ok := compressors[e]; ok {
+				o.encodings = append(o.encodings, e)
+			}
+		}
+	})
+}
+
+// WithMinSize sets the size in bytes under which the responses are not
+// compressed, 1024 by default. The size is known from the Content-Length
+// header set by rest.Respond, the other responses are buffered until the size
+// is reached.
+func WithMinSize(n int) CompressOption {
+	return compressOptionFunc(func(o *compressOptions) {
+		if n >= 0 {
+			o.minSize = n
+		}
+	})
+}
+
+// WithCompressibleTypes sets the media types which are compressed. A type
+// ending with /* matches all its subtypes, e.g. text/*. The default types are
+// the JSON, XML and text documents.
+func WithCompressibleTypes(types ...string) CompressOption {
+	return compressOptionFunc(func(o *compressOptions) {
+		o.types = types
+	})
+}
+
+// compressor is the encoder of a content coding.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressors holds the constructors of the encoders keyed by content coding.
+var compressors = map[string]func() compressor{
+	EncodingZstd: func() compressor {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+		return enc
+	},
+	EncodingBrotli: func() compressor {
+		return brotli.NewWriterLevel(nil, 4)
+	},
+	EncodingGzip: func() compressor {
+		enc, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return enc
+	},
+}
+
+// compressorPools holds the pools of encoders keyed by content coding, as
+// their allocation is expensive.
+var compressorPools = func() map[string]*sync.Pool {
+	pools := make(map[string]*sync.Pool, len(compressors))
+	for e, fn := range compressors {
+		fn := fn
+		pools[e] = &sync.Pool{New: func() interface{} { return fn() }}
+	}
+
+	return pools
+}()
+
+// Compress compresses the responses with the content coding preferred by the
+// client in its Accept-Encoding header, among zstd, br and gzip. Only the
+// responses of a compressible media type and of at least the minimum size are
+// compressed, and the Vary header is set on them so the caches keep the
+// representations apart. A strong ETag gets the content coding as suffix when
+// the response is compressed, e.g. "<tag>-gzip", and stays usable with
+// If-Match as rest.CheckPreconditions matches it against the ETag of the data.
+//
+// The streamed responses, e.g. rest.RespondStream and rest.NewSSE, are
+// compressed as they are flushed. The responses to HEAD and range requests,
+// and the responses already encoded by the handler are left untouched.
+//
+// The bytes recorded in the context values are the compressed bytes sent to
+// the client. It should be registered before the Errors middleware so the
+// error responses are compressed too.
+func Compress(opts ...CompressOption) rest.Middleware {
+	o := compressOptions{
+		encodings: []string{EncodingZstd, EncodingBrotli, EncodingGzip},
+		minSize:   defaultMinCompressSize,
+		types: []string{
+			"application/json",
+			"application/problem+json",
+			"application/x-ndjson",
+			"application/xml",
+			"application/problem+xml",
+			"application/javascript",
+			"image/svg+xml",
+			"text/*",
+		},
+	}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				return handler(ctx, w, r)
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				o:              &o,
+				encoding:       negotiateEncoding(r.Header.Get("Accept-Encoding"), o.encodings),
+				ifNoneMatch:    r.Header.Get("If-None-Match"),
+			}
+
+			err := handler(ctx, cw, r)
+
+			if cerr := cw.close(); cerr != nil && err == nil {
+				err = cerr
+			}
+
+			return err
+		})
+
+		return h
+	}
+
+	return m
+}
+
+// negotiateEncoding returns the content coding preferred by the client
+// according to the given Accept-Encoding header value, among the supported
+// ones. On a tie, the first supported coding wins. It returns an empty string
+// when the response must not be compressed.
+func negotiateEncoding(accept string, supported []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return ""
+	}
+
+	qs := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || v < 0 || v > 1 {
+				continue
+			}
+			q = v
+		}
+
+		qs[coding] = q
+	}
+
+	var best string
+	var bestQ float64
+	for _, e := range supported {
+		q, ok := qs[e]
+		if !ok {
+			q = qs["*"]
+		}
+
+		if q > bestQ {
+			best, bestQ = e, q
+		}
+	}
+
+	return best
+}
+
+// compressWriter compresses the response once its headers tell it is
+// eligible. When the size of the body is not known, the writes are buffered
+// until the minimum size is reached, the response is flushed or the handler
+// returns.
+type compressWriter struct {
+	http.ResponseWriter
+	o *compressOptions
+	// encoding is the content coding accepted by the client, none when empty.
+	encoding string
+	// ifNoneMatch is the If-None-Match header of the request, to answer a
+	// conditional read with the ETag of the compressed representation.
+	ifNoneMatch string
+
+	status      int
+	wroteHeader bool
+	started     bool
+	buf         []byte
+	enc         compressor
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	// Informational responses are not the final response.
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	if cw.started {
+		return
+	}
+
+	// The status is not sent yet, so the response can still be replaced, e.g.
+	// by the error response of a handler failing after WriteHeader. The body
+	// buffered so far belongs to the replaced response.
+	cw.buf = nil
+	cw.wroteHeader = true
+	cw.status = statusCode
+
+	switch {
+	case !cw.eligible(false):
+		cw.start(-1)
+	case cw.Header().Get("Content-Type") != "" && cw.Header().Get("Content-Length") != "":
+		n, err := strconv.Atoi(cw.Header().Get("Content-Length"))
+		if err != nil {
+			n = -1
+		}
+		cw.start(n)
+	}
+}
+
+// Write implements the http.ResponseWriter interface.
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.started {
+		return cw.write(b)
+	}
+
+	cw.buf = append(cw.buf, b...)
+	if len(cw.buf) < cw.o.minSize {
+		return len(b), nil
+	}
+
+	cw.start(-1)
+	if err := cw.flushBuffer(); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// FlushError flushes the response, used by http.ResponseController. The
+// response is compressed when eligible, whatever its size, as a flushed
+// response is a stream.
+func (cw *compressWriter) FlushError() error {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.started {
+		cw.start(-1)
+	}
+
+	if err := cw.flushBuffer(); err != nil {
+		return err
+	}
+
+	if cw.enc != nil {
+		if err := cw.enc.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Flush implements the http.Flusher interface.
+func (cw *compressWriter) Flush() {
+	_ = cw.FlushError()
+}
+
+// Unwrap returns the wrapped writer, used by http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close sends what is left of the response and releases the encoder. Nothing
+// is sent when the handler did not write anything.
+func (cw *compressWriter) close() error {
+	if !cw.started {
+		if !cw.wroteHeader {
+			return nil
+		}
+
+		cw.start(len(cw.buf))
+	}
+
+	err := cw.flushBuffer()
+
+	if cw.enc != nil {
+		if cerr := cw.enc.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+
+		cw.enc.Reset(nil)
+		compressorPools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+
+	return err
+}
+
+// eligible tells whether the response can be compressed according to its
+// headers. The content type of a response without one is sniffed from the
+// buffered body once complete is set, it is deemed eligible otherwise.
+func (cw *compressWriter) eligible(complete bool) bool {
+	if !bodyAllowed(cw.status) || cw.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+
+	ct := cw.Header().Get("Content-Type")
+	if ct == "" {
+		if !complete {
+			return true
+		}
+
+		// Set the content type net/http would have sniffed, as it can not
+		// sniff the compressed body.
+		ct = http.DetectContentType(cw.buf)
+		cw.Header().Set("Content-Type", ct)
+	}
+
+	return cw.compressible(ct)
+}
+
+// compressible tells whether the media type of the content type is one of the
+// compressible types.
+func (cw *compressWriter) compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, t := range cw.o.types {
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok {
+			if strings.HasPrefix(mt, prefix+"/") {
+				return true
+			}
+			continue
+		}
+
+		if mt == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// start sends the headers, with the encoding when the response is compressed.
+// size is the size of the body, -1 when unknown.
+func (cw *compressWriter) start(size int) {
+	cw.started = true
+
+	// The client holds the compressed representation it was sent before.
+	if cw.status == http.StatusNotModified && cw.encoding != "" {
+		h := cw.Header()
+		etag := h.Get("ETag")
+		if encoded := rest.EncodedETag(etag, cw.encoding); encoded != etag && strings.Contains(cw.ifNoneMatch, encoded) {
+			h.Set("ETag", encoded)
+			h.Add("Vary", "Accept-Encoding")
+		}
+	}
+
+	if cw.eligible(true) {
+		h := cw.Header()
+		h.Add("Vary", "Accept-Encoding")
+
+		if cw.encoding != "" && (size < 0 || size >= cw.o.minSize) {
+			h.Set("Content-Encoding", cw.encoding)
+			h.Del("Content-Length")
+
+			// The compressed representation is not byte for byte the same.
+			if etag := h.Get("ETag"); etag != "" {
+				h.Set("ETag", rest.EncodedETag(etag, cw.encoding))
+			}
+
+			cw.enc = compressorPools[cw.encoding].Get().(compressor)
+			cw.enc.Reset(cw.ResponseWriter)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// write writes the bytes to the client, through the encoder when the response
+// is compressed.
+func (cw *compressWriter) write(b []byte) (int, error) {
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+
+	return cw.ResponseWriter.Write(b)
+}
+
+// flushBuffer writes the buffered bytes.
+func (cw *compressWriter) flushBuffer() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	_, err := cw.write(cw.buf)
+	cw.buf = nil
+
+	return err
+}
+
+// bodyAllowed tells whether a response with the status code has a body.
+func bodyAllowed(statusCode int) bool {
+	switch {
+	case statusCode >= 100 && statusCode < 200:
+		return false
+	case statusCode == http.StatusNoContent, statusCode == http.StatusNotModified:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,313 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	// Third party packages
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// decompress returns the body of the response decoded with its content
+// coding.
+func decompress(t *testing.T, rr *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	var r io.Reader
+	switch rr.Header().Get("Content-Encoding") {
+	case "":
+		r = rr.Body
+	case EncodingGzip:
+		zr, err := gzip.NewReader(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case EncodingBrotli:
+		r = brotli.NewReader(rr.Body)
+	case EncodingZstd:
+		zr, err := zstd.NewReader(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		t.Fatalf("unexpected content coding %q", rr.Header().Get("Content-Encoding"))
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("compressible ", 200)
+
+	api := rest.New(make(chan os.Signal, 1), Compress(), Errors())
+	api.Handle(http.MethodGet, "/large", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("ETag", `"v1"`)
+		return rest.Respond(ctx, w, large, http.StatusOK)
+	})
+	api.Handle(http.MethodGet, "/small", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, "small", http.StatusOK)
+	})
+	api.Handle(http.MethodGet, "/binary", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "image/png")
+		_, err := w.Write([]byte(large))
+		return err
+	})
+	api.Handle(http.MethodGet, "/text", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		for i := 0; i < 200; i++ {
+			if _, err := io.WriteString(w, "compressible "); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	testCases := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		expectedEncoding string
+		expectedVary     bool
+	}{
+		{name: "Gzip", path: "/large", acceptEncoding: "gzip", expectedEncoding: EncodingGzip, expectedVary: true},
+		{name: "Brotli", path: "/large", acceptEncoding: "br", expectedEncoding: EncodingBrotli, expectedVary: true},
+		{name: "Zstd", path: "/large", acceptEncoding: "zstd", expectedEncoding: EncodingZstd, expectedVary: true},
+		{name: "Preference", path: "/large", acceptEncoding: "gzip, br;q=0.9, zstd;q=0.5", expectedEncoding: EncodingGzip, expectedVary: true},
+		{name: "Not Accepted", path: "/large", acceptEncoding: "", expectedVary: true},
+		{name: "Identity Only", path: "/large", acceptEncoding: "identity, *;q=0", expectedVary: true},
+		{name: "Under Minimum Size", path: "/small", acceptEncoding: "gzip", expectedVary: true},
+		{name: "Not Compressible", path: "/binary", acceptEncoding: "gzip"},
+		{name: "Unknown Size", path: "/text", acceptEncoding: "gzip", expectedEncoding: EncodingGzip, expectedVary: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+
+			if got := rr.Header().Get("Content-Encoding"); got != tc.expectedEncoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", tc.expectedEncoding, got)
+			}
+			if got := strings.Contains(rr.Header().Get("Vary"), "Accept-Encoding"); got != tc.expectedVary {
+				t.Errorf("Expected Vary on Accept-Encoding to be %v, got %q", tc.expectedVary, rr.Header().Get("Vary"))
+			}
+			if tc.expectedEncoding != "" && rr.Header().Get("Content-Length") != "" {
+				t.Error("Expected no Content-Length on a compressed response")
+			}
+
+			want := large
+			if tc.path == "/small" {
+				want = "small"
+			}
+			if body := decompress(t, rr); !strings.Contains(body, want) {
+				t.Errorf("Expected the body to be preserved, got %q", body)
+			}
+		})
+	}
+
+	t.Run("Encoded ETag", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/large", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+
+		if etag := rr.Header().Get("ETag"); etag != `"v1-gzip"` {
+			t.Errorf("Expected a strong ETag with the encoding, got %q", etag)
+		}
+	})
+}
+
+func TestCompressETag(t *testing.T) {
+	site := map[string]string{"name": strings.Repeat("compressible ", 200)}
+
+	api := rest.New(make(chan os.Signal, 1), Compress(), Errors())
+	api.Handle(http.MethodGet, "/sites/1", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, site, http.StatusOK)
+	}, rest.UseETags(rest.StrongETags))
+	api.Handle(http.MethodPut, "/sites/1", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		etag, err := rest.ComputeETag(ctx, site)
+		if err != nil {
+			return err
+		}
+
+		if err := rest.CheckPreconditions(r, etag, time.Time{}); err != nil {
+			return err
+		}
+
+		return rest.Respond(ctx, w, site, http.StatusOK)
+	}, rest.UseETags(rest.StrongETags))
+
+	serve := func(method, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/sites/1", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	etag := serve(http.MethodGet, "", "").Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `-gzip"`) {
+		t.Fatalf("Expected a strong ETag with the encoding, got %q", etag)
+	}
+
+	testCases := []struct {
+		name           string
+		method         string
+		header         string
+		value          string
+		expectedStatus int
+	}{
+		{name: "If-None-Match", method: http.MethodGet, header: "If-None-Match", value: etag, expectedStatus: http.StatusNotModified},
+		{name: "If-Match", method: http.MethodPut, header: "If-Match", value: etag, expectedStatus: http.StatusOK},
+		{name: "If-Match Weakened", method: http.MethodPut, header: "If-Match", value: "W/" + etag, expectedStatus: http.StatusPreconditionFailed},
+		{name: "If-Match Changed", method: http.MethodPut, header: "If-Match", value: `"other-gzip"`, expectedStatus: http.StatusPreconditionFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := serve(tc.method, tc.header, tc.value)
+
+			if rr.Code != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tc.expectedStatus)
+			}
+			if rr.Code != http.StatusPreconditionFailed && rr.Header().Get("ETag") != etag {
+				t.Errorf("Expected the ETag to be stable, got %q want %q", rr.Header().Get("ETag"), etag)
+			}
+		})
+	}
+}
+
+func TestCompressHandlerError(t *testing.T) {
+	api := rest.New(make(chan os.Signal, 1), Compress(), Errors())
+	api.Handle(http.MethodGet, "/header", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return errors.New("simulated handler error")
+	})
+	api.Handle(http.MethodGet, "/partial", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "text/plain")
+		if _, err := w.Write([]byte("partial")); err != nil {
+			return err
+		}
+		return errors.New("simulated handler error")
+	})
+
+	for _, path := range []string{"/header", "/partial"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusInternalServerError {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusInternalServerError)
+			}
+
+			body := decompress(t, rr)
+			if !strings.Contains(body, rest.CodeInternal) || strings.Contains(body, "partial") {
+				t.Errorf("Expected the error response only, got %q", body)
+			}
+		})
+	}
+}
+
+func TestCompressStream(t *testing.T) {
+	api := rest.New(make(chan os.Signal, 1), Compress())
+	api.Handle(http.MethodGet, "/events", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		sse, err := rest.NewSSE(ctx, w, r)
+		if err != nil {
+			return err
+		}
+
+		return sse.Send(rest.Event{ID: "1", Data: "hello"})
+	})
+
+	// flushRecorder records the body written at each flush.
+	rr := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	api.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != EncodingGzip {
+		t.Fatalf("Expected the stream to be compressed, got %v", rr.Header())
+	}
+
+	// The event must be readable as soon as it is flushed.
+	zr, err := gzip.NewReader(bytes.NewReader(rr.flushed[len(rr.flushed)-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 64)
+	n, _ := zr.Read(b)
+	if !strings.Contains(string(b[:n]), "data: hello") {
+		t.Errorf("Expected the event to be flushed, got %q", b[:n])
+	}
+
+	if body := decompress(t, rr.ResponseRecorder); !strings.Contains(body, "id: 1\ndata: hello\n\n") {
+		t.Errorf("Expected the event in the stream, got %q", body)
+	}
+}
+
+// flushRecorder is a recorder keeping a copy of the body at each flush.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed [][]byte
+}
+
+// Flush implements the http.Flusher interface.
+func (fr *flushRecorder) Flush() {
+	fr.ResponseRecorder.Flush()
+	fr.flushed = append(fr.flushed, bytes.Clone(fr.Body.Bytes()))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+	testCases := []struct {
+		accept   string
+		expected string
+	}{
+		{accept: "", expected: ""},
+		{accept: "gzip, deflate, br", expected: EncodingBrotli},
+		{accept: "gzip;q=1.0, br;q=0.5", expected: EncodingGzip},
+		{accept: "*", expected: EncodingZstd},
+		{accept: "*;q=0.5, gzip", expected: EncodingGzip},
+		{accept: "gzip;q=0", expected: ""},
+		{accept: "deflate", expected: ""},
+		{accept: "GZIP", expected: EncodingGzip},
+		{accept: "gzip;q=abc, br", expected: EncodingBrotli},
+	}
+
+	for _, tc := range testCases {
+		if got := negotiateEncoding(tc.accept, supported); got != tc.expected {
+			t.Errorf("negotiateEncoding(%q): got %q want %q", tc.accept, got, tc.expected)
+		}
+	}
+}
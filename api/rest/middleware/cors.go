@@ -0,0 +1,272 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// CORS headers.
+const (
+	headerOrigin                        = "Origin"
+	headerAccessControlRequestMethod    = "Access-Control-Request-Method"
+	headerAccessControlRequestHeaders   = "Access-Control-Request-Headers"
+	headerAccessControlAllowOrigin      = "Access-Control-Allow-Origin"
+	headerAccessControlAllowMethods     = "Access-Control-Allow-Methods"
+	headerAccessControlAllowHeaders     = "Access-Control-Allow-Headers"
+	headerAccessControlAllowCredentials = "Access-Control-Allow-Credentials"
+	headerAccessControlExposeHeaders    = "Access-Control-Expose-Headers"
+	headerAccessControlMaxAge           = "Access-Control-Max-Age"
+)
+
+// defaultCORSMethods are the methods allowed when none is configured.
+var defaultCORSMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// CORSConfig holds the settings of the CORS middleware.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to make cross-origin requests.
+	// An origin is either exact, e.g. "https://example.com", a wildcard
+	// subdomain, e.g. "https://*.example.com", or "*" to allow any origin.
+	AllowedOrigins []string
+	// AllowedOriginPatterns are the regular expressions matching the origins
+	// allowed to make cross-origin requests, on top of AllowedOrigins. They
+	// must match the whole origin, e.g. `https://example\.com` does not
+	// allow https://example.com.evil.com.
+	AllowedOriginPatterns []*regexp.Regexp
+	// AllowedMethods are the methods allowed for the cross-origin requests.
+	// GET, HEAD, POST, PUT, PATCH and DELETE are allowed when empty.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed for the cross-origin
+	// requests, "*" allowing any header. The CORS-safelisted headers are
+	// always allowed.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers the browser exposes to the
+	// client code.
+	ExposedHeaders []string
+	// AllowCredentials allows the cross-origin requests to send cookies and
+	// authorization headers.
+	AllowCredentials bool
+	// MaxAge is how long the browser can cache the result of a preflight
+	// request. The browser default applies when it is zero.
+	MaxAge time.Duration
+}
+
+// cors holds the CORS settings ready to be matched.
+type cors struct {
+	cfg       CORSConfig
+	anyOrigin bool
+	origins   map[string]bool
+	wildcards [][2]string
+	patterns  []*regexp.Regexp
+	methods   map[string]bool
+	anyHeader bool
+	headers   map[string]bool
+}
+
+// CORS applies the cross-origin resource sharing policy of the config. It
+// answers the preflight requests of every route, the API routing the OPTIONS
+// requests of all its paths through its general middleware, so it must be
+// registered in the API general middleware, before the Errors middleware so
+// the error responses carry the CORS headers.
+func CORS(cfg CORSConfig) rest.Middleware {
+	c := newCORS(cfg)
+
+	// This is the actual middleware function to be executed.
+	m := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			origin := r.Header.Get(headerOrigin)
+
+			if r.Method == http.MethodOptions && origin != "" && r.Header.Get(headerAccessControlRequestMethod) != "" {
+				c.preflight(w, r, origin)
+				return nil
+			}
+
+			if !c.anyOrigin || c.cfg.AllowCredentials {
+				w.Header().Add("Vary", headerOrigin)
+			}
+
+			if origin != "" && c.allowOrigin(origin) {
+				c.setOrigin(w, origin)
+
+				if len(c.cfg.ExposedHeaders) > 0 {
+					w.Header().Set(headerAccessControlExposeHeaders, strings.Join(c.cfg.ExposedHeaders, ", "))
+				}
+			}
+
+			return handler(ctx, w, r)
+		})
+
+		return h
+	}
+
+	return m
+}
+
+// newCORS prepares the config to be matched.
+func newCORS(cfg CORSConfig) *cors {
+	c := &cors{
+		cfg:     cfg,
+		origins: make(map[string]bool),
+		methods: make(map[string]bool),
+		headers: make(map[string]bool),
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(origin)
+
+		switch {
+		case origin == "*":
+			c.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			c.wildcards = append(c.wildcards, [2]string{prefix, suffix})
+		default:
+			c.origins[origin] = true
+		}
+	}
+
+	// The patterns are anchored so they match the whole origin.
+	for _, re := range cfg.AllowedOriginPatterns {
+		c.patterns = append(c.patterns, regexp.MustCompile(`^(?:`+re.String()+`)$`))
+	}
+
+	if len(c.cfg.AllowedMethods) == 0 {
+		c.cfg.AllowedMethods = defaultCORSMethods
+	}
+	for _, method := range c.cfg.AllowedMethods {
+		c.methods[strings.ToUpper(method)] = true
+	}
+
+	for _, header := range cfg.AllowedHeaders {
+		if header == "*" {
+			c.anyHeader = true
+		}
+		c.headers[http.CanonicalHeaderKey(header)] = true
+	}
+
+	return c
+}
+
+// preflight answers a preflight request. The CORS headers are only sent when
+// the request is allowed, which makes the browser fail the actual request
+// otherwise.
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	h := w.Header()
+	h.Add("Vary", headerOrigin)
+	h.Add("Vary", headerAccessControlRequestMethod)
+	h.Add("Vary", headerAccessControlRequestHeaders)
+
+	method := r.Header.Get(headerAccessControlRequestMethod)
+	requested := requestedHeaders(r.Header.Values(headerAccessControlRequestHeaders))
+
+	if c.allowOrigin(origin) && c.methods[strings.ToUpper(method)] && c.allowHeaders(requested) {
+		c.setOrigin(w, origin)
+		h.Set(headerAccessControlAllowMethods, strings.Join(c.cfg.AllowedMethods, ", "))
+
+		if len(requested) > 0 {
+			h.Set(headerAccessControlAllowHeaders, strings.Join(requested, ", "))
+		}
+
+		if c.cfg.MaxAge > 0 {
+			h.Set(headerAccessControlMaxAge, strconv.Itoa(int(c.cfg.MaxAge.Seconds())))
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOrigin sets the allowed origin of the response.
+func (c *cors) setOrigin(w http.ResponseWriter, origin string) {
+	// The wildcard is not allowed for requests with credentials.
+	if c.anyOrigin && !c.cfg.AllowCredentials {
+		w.Header().Set(headerAccessControlAllowOrigin, "*")
+	} else {
+		w.Header().Set(headerAccessControlAllowOrigin, origin)
+	}
+
+	if c.cfg.AllowCredentials {
+		w.Header().Set(headerAccessControlAllowCredentials, "true")
+	}
+}
+
+// allowOrigin reports whether the origin is allowed.
+func (c *cors) allowOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+	if c.origins[lower] {
+		return true
+	}
+
+	for _, wc := range c.wildcards {
+		prefix, suffix := wc[0], wc[1]
+		if len(lower) > len(prefix)+len(suffix) && strings.HasPrefix(lower, prefix) && strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+
+	for _, re := range c.patterns {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowHeaders reports whether the requested headers are all allowed.
+func (c *cors) allowHeaders(requested []string) bool {
+	if c.anyHeader {
+		return true
+	}
+
+	for _, header := range requested {
+		if !c.headers[header] && !safelistedHeader(header) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requestedHeaders returns the canonical header names listed in the
+// Access-Control-Request-Headers values.
+func requestedHeaders(values []string) []string {
+	var headers []string
+	for _, value := range values {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.TrimSpace(header); header != "" {
+				headers = append(headers, http.CanonicalHeaderKey(header))
+			}
+		}
+	}
+
+	return headers
+}
+
+// safelistedHeader reports whether the header is a CORS-safelisted request
+// header, which the browser sends without asking.
+func safelistedHeader(header string) bool {
+	switch header {
+	case "Accept", "Accept-Language", "Content-Language", "Content-Type":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,166 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+func newTestCORSAPI(cfg CORSConfig) *rest.API {
+	api := rest.New(make(chan os.Signal, 1), CORS(cfg), Errors())
+	api.Handle(http.MethodGet, "/sites/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, "site", http.StatusOK)
+	})
+	api.Group("/v1").Handle(http.MethodPost, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.NewRequestError(errors.New("invalid site"), http.StatusBadRequest, "bad_request")
+	})
+
+	return api
+}
+
+func TestCORSOrigins(t *testing.T) {
+	api := newTestCORSAPI(CORSConfig{
+		AllowedOrigins: []string{"https://example.com", "https://*.example.org"},
+		AllowedOriginPatterns: []*regexp.Regexp{
+			regexp.MustCompile(`^https://pr-\d+\.preview\.dev$`),
+			regexp.MustCompile(`https://example\.com`),
+			regexp.MustCompile(`https://example\.net|https://app\.example\.net`),
+		},
+		ExposedHeaders: []string{"X-Request-ID"},
+	})
+
+	testCases := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "Exact", origin: "https://example.com", allowed: true},
+		{name: "Exact Other Scheme", origin: "http://example.com", allowed: false},
+		{name: "Wildcard Subdomain", origin: "https://api.example.org", allowed: true},
+		{name: "Wildcard Apex", origin: "https://example.org", allowed: false},
+		{name: "Pattern", origin: "https://pr-42.preview.dev", allowed: true},
+		{name: "Unanchored Pattern", origin: "https://example.net", allowed: true},
+		{name: "Unanchored Pattern Alternative", origin: "https://app.example.net", allowed: true},
+		{name: "Unanchored Pattern Suffix", origin: "https://example.com.evil.com", allowed: false},
+		{name: "Unanchored Pattern Alternative Suffix", origin: "https://example.net.evil.com", allowed: false},
+		{name: "Unanchored Pattern Prefix", origin: "https://evil.com/https://example.net", allowed: false},
+		{name: "Unknown", origin: "https://evil.com", allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sites/1", nil)
+			req.Header.Set("Origin", tc.origin)
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+
+			got := rr.Header().Get("Access-Control-Allow-Origin")
+			if tc.allowed && got != tc.origin {
+				t.Errorf("Expected origin %q to be allowed, got %q", tc.origin, got)
+			}
+			if !tc.allowed && got != "" {
+				t.Errorf("Expected origin %q to be rejected, got %q", tc.origin, got)
+			}
+			if tc.allowed && rr.Header().Get("Access-Control-Expose-Headers") != "X-Request-ID" {
+				t.Error("Expected the exposed headers to be sent")
+			}
+			if rr.Header().Get("Vary") != "Origin" {
+				t.Errorf("Expected Vary: Origin, got %q", rr.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	api := newTestCORSAPI(CORSConfig{
+		AllowedOrigins:   []string{"https://example.com"},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders:   []string{"Authorization"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	testCases := []struct {
+		name    string
+		path    string
+		origin  string
+		method  string
+		headers string
+		allowed bool
+	}{
+		{name: "Allowed", path: "/sites/1", origin: "https://example.com", method: "GET", headers: "authorization, content-type", allowed: true},
+		{name: "Group Route", path: "/v1/sites", origin: "https://example.com", method: "POST", allowed: true},
+		{name: "Method Not Allowed", path: "/sites/1", origin: "https://example.com", method: "DELETE"},
+		{name: "Header Not Allowed", path: "/sites/1", origin: "https://example.com", method: "GET", headers: "X-Secret"},
+		{name: "Origin Not Allowed", path: "/sites/1", origin: "https://evil.com", method: "GET"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tc.path, nil)
+			req.Header.Set("Origin", tc.origin)
+			req.Header.Set("Access-Control-Request-Method", tc.method)
+			if tc.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tc.headers)
+			}
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusNoContent {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+			}
+
+			h := rr.Header()
+			if !tc.allowed {
+				if h.Get("Access-Control-Allow-Origin") != "" {
+					t.Errorf("Expected the preflight to be rejected, got %v", h)
+				}
+				return
+			}
+
+			expected := map[string]string{
+				"Access-Control-Allow-Origin":      tc.origin,
+				"Access-Control-Allow-Methods":     "GET, POST",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Max-Age":           "600",
+			}
+			if tc.headers != "" {
+				expected["Access-Control-Allow-Headers"] = "Authorization, Content-Type"
+			}
+			for name, value := range expected {
+				if got := h.Get(name); got != value {
+					t.Errorf("Expected %s %q, got %q", name, value, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	api := newTestCORSAPI(CORSConfig{AllowedOrigins: []string{"*"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/sites", nil)
+	req.Header.Set("Origin", "https://example.com")
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if got := rr.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected the error response to allow any origin, got %q", got)
+	}
+	if got := rr.Header().Get("Vary"); got != "" {
+		t.Errorf("Expected no Vary header, got %q", got)
+	}
+}
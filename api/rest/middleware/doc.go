@@ -1,5 +1,16 @@
 // Package middleware provides the middleware for the application.
 //
 // It provides a way to handle errors.
+// It provides a way to recover from panics.
+// It provides a way to record the request metrics.
 // It provides a way to log the request.
+// It provides a way to allow cross-origin requests from browsers (CORS).
+// It provides a way to authenticate the requests with JWT, API keys or HMAC
+// signatures.
+// It provides a way to authorize the requests with scopes, roles or policies.
+// It provides a way to limit the rate of the requests of each client.
+// It provides a way to bound the time spent handling the requests.
+// It provides a way to make the unsafe requests safe to retry with idempotency
+// keys.
+// It provides a way to compress the responses with zstd, brotli or gzip.
 package middleware
@@ -6,11 +6,16 @@ import (
 	"fmt"
 	"net/http"
 	// Pantheon internal package
-	"github.com/coderkakarrots/go-pkg-lib/api/rest"
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
 )
 
 // Errors handles errors coming out of the call chain. It detects normal
 // application errors which are used to respond to the client in a uniform way.
+// Unexpected errors (status code 500) are returned without leaking the
+// internal message to the client. Shutdown errors are returned up the chain
+// so the API can signal the shutdown. When the headers were already sent, no
+// error response can be written anymore: the error is returned up the chain
+// to the error handler of the API.
 func Errors() rest.Middleware {
 	// This is the actual middleware function to be executed.
 	m := func(handler rest.Handler) rest.Handler {
@@ -19,12 +24,39 @@ func Errors() rest.Middleware {
 			// Run the next handler and catch any propagated error.
 			err := handler(ctx, w, r)
 			if err != nil {
-				_ = rest.SetStatusCode(ctx, http.StatusInternalServerError)
+				// The status code is already on the wire.
+				if v, verr := rest.GetContextValues(ctx); verr == nil && v.HeadersSent {
+					_ = rest.SetIsError(ctx)
+					return err
+				}
+
+				var er rest.ErrorResponse
+				var status int
+
+				// Build out the error response based on the error type.
+				if re := rest.GetRequestError(err); re != nil {
+					er = re.Response()
+					status = re.StatusCode
+				} else {
+					er = rest.ErrorResponse{
+						Code:    rest.CodeInternal,
+						Message: rest.ErrInternalServer.Error(),
+					}
+					status = http.StatusInternalServerError
+				}
+
+				_ = rest.SetStatusCode(ctx, status)
 				_ = rest.SetIsError(ctx)
 				// Respond with the error back to the client
-				if err := rest.Respond(ctx, w, err.Error(), http.StatusInternalServerError); err != nil {
+				if err := rest.Respond(ctx, w, er, status); err != nil {
 					return fmt.Errorf("error responding with error: %w", err)
 				}
+
+				// If we receive the shutdown error we need to return it
+				// back to the base handler to shut down the service.
+				if rest.IsShutdown(err) {
+					return err
+				}
 			}
 
 			return nil
@@ -7,48 +7,169 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
 	// Pantheon internal package
-	"github.com/coderkakarrots/go-pkg-lib/api/rest"
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
 )
 
 func TestErrorsMiddleware(t *testing.T) {
-
-	// Create a mock handler that returns an error
-	mockHandlerWithError := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-		return errors.New("simulated handler error")
+	testCases := []struct {
+		name           string
+		handlerErr     error
+		expectedStatus int
+		expectedCode   string
+		expectedMsg    string
+	}{
+		{
+			name:           "Unknown Error",
+			handlerErr:     errors.New("simulated handler error"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   rest.CodeInternal,
+			expectedMsg:    rest.ErrInternalServer.Error(),
+		},
+		{
+			name:           "Request Error",
+			handlerErr:     rest.NewRequestError(errors.New("site not found"), http.StatusNotFound, "not_found"),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   "not_found",
+			expectedMsg:    "site not found",
+		},
+		{
+			name: "Wrapped Request Error",
+			handlerErr: fmt.Errorf("handler: %w",
+				rest.NewRequestError(errors.New("name is required"), http.StatusBadRequest, "validation_failed")),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   "validation_failed",
+			expectedMsg:    "name is required",
+		},
 	}
 
-	req, err := http.NewRequest("GET", "/", nil)
-	if err != nil {
-		t.Fatal(err)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Create a mock handler that returns an error
+			mockHandlerWithError := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return tc.handlerErr
+			}
+
+			req, err := http.NewRequest("GET", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			shutdown := make(chan os.Signal, 1)
+
+			api := rest.New(shutdown)
+
+			api.Handle(http.MethodGet, "/", mockHandlerWithError, Errors()) // Register the mock handler
+
+			// Call ServeHTTP directly on the API instance
+			api.ServeHTTP(rr, req)
+
+			// Check the response status code
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					rr.Code, tc.expectedStatus)
+			}
+
+			body := rr.Body.String()
+			response := struct {
+				Success bool               `json:"success"`
+				Data    interface{}        `json:"data"`
+				Errors  rest.ErrorResponse `json:"errors"`
+			}{}
+			err = json.Unmarshal([]byte(body), &response)
+			if err != nil {
+				t.Fatal("could not unmarshal response body")
+			}
+			if response.Success != false || response.Data != nil {
+				t.Errorf("handler returned wrong response: got %v", body)
+			}
+			if response.Errors.Code != tc.expectedCode || response.Errors.Message != tc.expectedMsg {
+				t.Errorf("handler returned wrong errors: got %+v", response.Errors)
+			}
+		})
 	}
+}
+
+func TestErrorsMiddlewareShutdown(t *testing.T) {
+	handlerErr := rest.NewShutdownError("integrity issue")
+	h := Errors()(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return handlerErr
+	})
 
 	rr := httptest.NewRecorder()
 	shutdown := make(chan os.Signal, 1)
-
 	api := rest.New(shutdown)
+	api.Handle(http.MethodGet, "/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		err := h(ctx, w, r)
+		if err != handlerErr {
+			t.Errorf("Expected shutdown error to be returned, got %v", err)
+		}
+		return nil
+	})
+	api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
 
-	api.Handle(http.MethodGet, "/", mockHandlerWithError, Errors()) // Register the mock handler
-
-	// Call ServeHTTP directly on the API instance
-	api.ServeHTTP(rr, req)
-
-	// Check the response status code
 	if rr.Code != http.StatusInternalServerError {
 		t.Errorf("handler returned wrong status code: got %v want %v",
 			rr.Code, http.StatusInternalServerError)
 	}
-	body := rr.Body.String()
-	response := rest.Response{}
-	err = json.Unmarshal([]byte(body), &response)
-	if err != nil {
+}
+
+func TestErrorsMiddlewareProblem(t *testing.T) {
+	shutdown := make(chan os.Signal, 1)
+	api := rest.NewWithOptions(shutdown, rest.WithMiddleware(Errors()), rest.WithEncoder(rest.ProblemEncoder{}))
+	api.Handle(http.MethodGet, "/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errors.New("simulated handler error")
+	})
+
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if ct := rr.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Expected Content-Type 'application/problem+json', got '%s'", ct)
+	}
+
+	var p rest.Problem
+	if err := json.Unmarshal(rr.Body.Bytes(), &p); err != nil {
 		t.Fatal("could not unmarshal response body")
 	}
-	if response.Success != false || response.Data != nil || response.Errors != "simulated handler error" {
-		t.Errorf("handler returned wrong response: got %v", body)
+	if p.Status != http.StatusInternalServerError || p.Detail != rest.ErrInternalServer.Error() {
+		t.Errorf("handler returned wrong response: got %v", rr.Body.String())
+	}
+}
+
+func TestErrorsMiddlewareHeadersSent(t *testing.T) {
+	handlerErr := errors.New("connection lost")
+
+	var got error
+	shutdown := make(chan os.Signal, 1)
+	api := rest.NewWithOptions(shutdown,
+		rest.WithMiddleware(Errors()),
+		rest.WithErrorHandler(func(ctx context.Context, r *http.Request, err error) {
+			got = err
+		}),
+	)
+	api.Handle(http.MethodGet, "/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("partial"))
+		return handlerErr
+	})
+
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusAccepted)
+	}
+	if rr.Body.String() != "partial" {
+		t.Errorf("Expected no error response to be written, got %q", rr.Body.String())
+	}
+	if got != handlerErr {
+		t.Errorf("Expected the error to reach the error handler, got %v", got)
 	}
 }
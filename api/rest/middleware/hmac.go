@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	// Standard library packages
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// HMAC signed requests.
+//
+// The request carries the ID of the key and the signature in the
+// Authorization header, along with the time of the signature:
+//
+//	Authorization: HMAC-SHA256 KeyId=<key id>,Signature=<base64 signature>
+//	X-Signature-Timestamp: <unix time in seconds>
+//
+// The signature is the HMAC-SHA256 of the lines, joined by "\n", of the
+// method, the request URI, the timestamp and the hex encoded SHA-256 of the
+// body.
+const (
+	// HMACScheme is the authorization scheme of the HMAC signed requests.
+	HMACScheme = "HMAC-SHA256"
+	// HeaderSignatureTimestamp is the header carrying the signature time.
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+)
+
+// defaultMaxClockSkew is the default maximum age of a signature.
+const defaultMaxClockSkew = 5 * time.Minute
+
+// HMACKey is a shared secret used to sign the requests.
+type HMACKey struct {
+	// Secret is the key of the HMAC.
+	Secret []byte
+	// Principal is the identity of the requests signed with the key.
+	Principal rest.Principal
+}
+
+// HMACOption configures the HMACVerifier.
+type HMACOption interface {
+	apply(*HMACVerifier)
+}
+
+type hmacOptionFunc func(*HMACVerifier)
+
+func (f hmacOptionFunc) apply(v *HMACVerifier) { f(v) }
+
+// WithMaxClockSkew sets how far the signature time can be from the server
+// time, 5 minutes by default.
+func WithMaxClockSkew(d time.Duration) HMACOption {
+	return hmacOptionFunc(func(v *HMACVerifier) {
+		if d > 0 {
+			v.maxSkew = d
+		}
+	})
+}
+
+// HMACVerifier authenticates the requests signed with a shared secret.
+type HMACVerifier struct {
+	keys    map[string]HMACKey
+	maxSkew time.Duration
+	maxBody int64
+	now     func() time.Time
+}
+
+// NewHMACVerifier creates a Verifier for the requests signed with the given
+// keys, keyed by key ID. The body is read to be verified, up to
+// rest.DefaultMaxBodyBytes, and made available again to the handler.
+func NewHMACVerifier(keys map[string]HMACKey, opts ...HMACOption) *HMACVerifier {
+	v := &HMACVerifier{
+		keys:    keys,
+		maxSkew: defaultMaxClockSkew,
+		maxBody: rest.DefaultMaxBodyBytes,
+		now:     time.Now,
+	}
+
+	for _, opt := range opts {
+		opt.apply(v)
+	}
+
+	return v
+}
+
+// Challenge implements the Challenger interface.
+func (v *HMACVerifier) Challenge() string {
+	return HMACScheme
+}
+
+// Verify implements the Verifier interface.
+func (v *HMACVerifier) Verify(r *http.Request) (*rest.Principal, error) {
+	scheme, params, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, HMACScheme) {
+		return nil, ErrNoCredentials
+	}
+
+	keyID, signature := parseHMACParams(params)
+	key, ok := v.keys[keyID]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	timestamp := r.Header.Get(HeaderSignatureTimestamp)
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if skew := v.now().Sub(time.Unix(ts, 0)); skew > v.maxSkew || skew < -v.maxSkew {
+		return nil, ErrInvalidCredentials
+	}
+
+	bodyHash, err := v.hashBody(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hmac.Equal(sig, signRequest(key.Secret, r.Method, r.URL.RequestURI(), timestamp, bodyHash)) {
+		return nil, ErrInvalidCredentials
+	}
+
+	p := key.Principal
+	p.Method = "hmac"
+
+	return &p, nil
+}
+
+// hashBody returns the hex encoded SHA-256 of the body, which is restored
+// for the handler.
+func (v *HMACVerifier) hashBody(r *http.Request) (string, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return sha256Hex(nil), nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, v.maxBody+1))
+	if err != nil {
+		return "", fmt.Errorf("read body fail: %w", err)
+	}
+
+	if int64(len(body)) > v.maxBody {
+		return "", ErrInvalidCredentials
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return sha256Hex(body), nil
+}
+
+// SignRequest signs the request with the key, for the clients of an API
+// using the HMACVerifier. The body is read and restored.
+func SignRequest(r *http.Request, keyID string, secret []byte) error {
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("read body fail: %w", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	sig := signRequest(secret, r.Method, r.URL.RequestURI(), timestamp, sha256Hex(body))
+
+	r.Header.Set(HeaderSignatureTimestamp, timestamp)
+	r.Header.Set("Authorization", fmt.Sprintf("%s KeyId=%s,Signature=%s",
+		HMACScheme, keyID, base64.StdEncoding.EncodeToString(sig)))
+
+	return nil
+}
+
+// signRequest returns the signature of the request parts.
+func signRequest(secret []byte, method string, uri string, timestamp string, bodyHash string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{method, uri, timestamp, bodyHash}, "\n")))
+
+	return mac.Sum(nil)
+}
+
+// parseHMACParams returns the key ID and the signature of the Authorization
+// header parameters.
+func parseHMACParams(params string) (keyID string, signature string) {
+	for _, param := range strings.Split(params, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		switch name {
+		case "KeyId":
+			keyID = value
+		case "Signature":
+			signature = value
+		}
+	}
+
+	return keyID, signature
+}
+
+// sha256Hex returns the hex encoded SHA-256 of b.
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,435 @@
+package middleware
+
+import (
+	// Standard library packages
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// Idempotency headers.
+const (
+	// HeaderIdempotencyKey is the header carrying the key identifying the
+	// retries of a request.
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// HeaderIdempotentReplayed is set on the responses replayed from the
+	// store.
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// Default settings of the Idempotency middleware.
+const (
+	defaultIdempotencyTTL     = 24 * time.Hour
+	defaultIdempotencyLockTTL = time.Minute
+	maxIdempotencyKeyBytes    = 255
+	maxIdempotencyBody        = 1 << 20
+)
+
+var (
+	// ErrIdempotencyConflict is the error returned when a request with the
+	// same key is still in progress.
+	ErrIdempotencyConflict = errors.New("a request with the same idempotency key is in progress")
+
+	// ErrIdempotencyMismatch is the error returned when a key is reused for a
+	// different request.
+	ErrIdempotencyMismatch = errors.New("the idempotency key was used for a different request")
+)
+
+// IdempotentResponse is a response captured to be replayed.
+type IdempotentResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// IdempotencyRecord is the state of an idempotency key.
+type IdempotencyRecord struct {
+	// Fingerprint identifies the request the key was first used for.
+	Fingerprint string
+	// Completed reports whether the response is captured, the request being
+	// in progress otherwise.
+	Completed bool
+	// Response is the captured response of a completed request.
+	Response IdempotentResponse
+}
+
+// IdempotencyStore keeps the idempotency records.
+type IdempotencyStore interface {
+	// Reserve stores the record for the key unless one exists. It returns
+	// false along the existing record in that case.
+	Reserve(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, bool, error)
+	// Save replaces the record of the key.
+	Save(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) error
+	// Delete removes the record of the key.
+	Delete(ctx context.Context, key string) error
+}
+
+// IdempotencyOption configures the Idempotency middleware.
+type IdempotencyOption interface {
+	apply(*idempotencyOptions)
+}
+
+// idempotencyOptions holds the settings of the Idempotency middleware.
+type idempotencyOptions struct {
+	store        IdempotencyStore
+	ttl          time.Duration
+	lockTTL      time.Duration
+	maxBodyBytes int64
+}
+
+type idempotencyOptionFunc func(*idempotencyOptions)
+
+func (f idempotencyOptionFunc) apply(o *idempotencyOptions) { f(o) }
+
+// WithIdempotencyStore sets the store of the records. By default, every
+// Idempotency middleware keeps its records in memory.
+func WithIdempotencyStore(store IdempotencyStore) IdempotencyOption {
+	return idempotencyOptionFunc(func(o *idempotencyOptions) {
+		if store != nil {
+			o.store = store
+		}
+	})
+}
+
+// WithIdempotencyTTL sets how long the responses are replayed, 24 hours by
+// default.
+func WithIdempotencyTTL(d time.Duration) IdempotencyOption {
+	return idempotencyOptionFunc(func(o *idempotencyOptions) {
+		if d > 0 {
+			o.ttl = d
+		}
+	})
+}
+
+// WithIdempotencyLockTTL sets how long the key of a request in progress is
+// held, 1 minute by default. It should exceed the duration of the longest
+// request, and lets the request be retried once expired when the instance
+// serving it went away.
+func WithIdempotencyLockTTL(d time.Duration) IdempotencyOption {
+	return idempotencyOptionFunc(func(o *idempotencyOptions) {
+		if d > 0 {
+			o.lockTTL = d
+		}
+	})
+}
+
+// WithIdempotencyMaxBodyBytes sets the maximum size of the request body read
+// to fingerprint the request, rest.DefaultMaxBodyBytes by default. It should
+// match the limit given to rest.Decode with rest.WithMaxBodyBytes, as the
+// larger bodies fail with a 413.
+func WithIdempotencyMaxBodyBytes(n int64) IdempotencyOption {
+	return idempotencyOptionFunc(func(o *idempotencyOptions) {
+		if n > 0 {
+			o.maxBodyBytes = n
+		}
+	})
+}
+
+// Idempotency makes the unsafe requests carrying the Idempotency-Key header
+// safe to retry. The response of the first request completing is captured
+// and replayed for the requests with the same key, which are scoped by
+// authenticated principal. A request with the key of a request in progress
+// fails with a 409 and a key reused for another method, path or body fails
+// with a 422.
+//
+// The responses of the failed requests, the 5xx and the errors returned by
+// the handler, are not captured so the request can be retried. It should be
+// registered after the Errors and Authenticate middleware.
+func Idempotency(opts ...IdempotencyOption) rest.Middleware {
+	o := idempotencyOptions{
+		ttl:          defaultIdempotencyTTL,
+		lockTTL:      defaultIdempotencyLockTTL,
+		maxBodyBytes: rest.DefaultMaxBodyBytes,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	if o.store == nil {
+		o.store = NewMemoryIdempotencyStore()
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			key := r.Header.Get(HeaderIdempotencyKey)
+			if key == "" || safeMethod(r.Method) {
+				return handler(ctx, w, r)
+			}
+
+			if len(key) > maxIdempotencyKeyBytes {
+				return rest.NewRequestError(
+					fmt.Errorf("the idempotency key must not exceed %d bytes", maxIdempotencyKeyBytes),
+					http.StatusBadRequest,
+					rest.CodeInvalidParameter,
+				)
+			}
+
+			fingerprint, err := requestFingerprint(r, o.maxBodyBytes)
+			if err != nil {
+				return err
+			}
+
+			if p, ok := rest.GetPrincipal(ctx); ok {
+				key = p.Method + ":" + p.Subject + ":" + key
+			}
+
+			rec, reserved, err := o.store.Reserve(ctx, key, IdempotencyRecord{Fingerprint: fingerprint}, o.lockTTL)
+			if err != nil {
+				return fmt.Errorf("idempotency reserve: %w", err)
+			}
+
+			if !reserved {
+				switch {
+				case rec.Fingerprint != fingerprint:
+					return rest.NewRequestError(ErrIdempotencyMismatch, http.StatusUnprocessableEntity, rest.CodeIdempotencyMismatch)
+				case !rec.Completed:
+					return rest.NewRequestError(ErrIdempotencyConflict, http.StatusConflict, rest.CodeIdempotencyConflict)
+				default:
+					return replay(w, rec.Response)
+				}
+			}
+
+			// Let the request be retried when the handler panics, the panic is
+			// left to the middleware recovering from it.
+			defer func() {
+				if p := recover(); p != nil {
+					_ = o.store.Delete(context.WithoutCancel(ctx), key)
+					panic(p)
+				}
+			}()
+
+			cw := &captureWriter{ResponseWriter: w}
+
+			err = handler(ctx, cw, r)
+
+			// Let the failed requests be retried.
+			if err != nil || cw.statusCode == 0 || cw.statusCode >= http.StatusInternalServerError || cw.overflow {
+				if dErr := o.store.Delete(context.WithoutCancel(ctx), key); dErr != nil {
+					return errors.Join(err, fmt.Errorf("idempotency delete: %w", dErr))
+				}
+
+				return err
+			}
+
+			rec = IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Completed:   true,
+				Response: IdempotentResponse{
+					StatusCode: cw.statusCode,
+					Header:     cw.header,
+					Body:       cw.body.Bytes(),
+				},
+			}
+
+			if err := o.store.Save(ctx, key, rec, o.ttl); err != nil {
+				return fmt.Errorf("idempotency save: %w", err)
+			}
+
+			return nil
+		})
+
+		return h
+	}
+
+	return m
+}
+
+// safeMethod reports whether the method is safe, hence idempotent already.
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// requestFingerprint returns the hash of the method, URI and body of the
+// request. The body, up to maxBodyBytes, is restored for the handler.
+func requestFingerprint(r *http.Request, maxBodyBytes int64) (string, error) {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+		if err != nil {
+			return "", fmt.Errorf("read body fail: %w", err)
+		}
+
+		if int64(len(body)) > maxBodyBytes {
+			return "", rest.NewRequestError(
+				fmt.Errorf("request body must not exceed %d bytes", maxBodyBytes),
+				http.StatusRequestEntityTooLarge,
+				rest.CodeRequestTooLarge,
+			)
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		h.Write(body)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// replay writes the captured response.
+func replay(w http.ResponseWriter, resp IdempotentResponse) error {
+	hdr := w.Header()
+	for k, vv := range resp.Header {
+		hdr[k] = append([]string(nil), vv...)
+	}
+	hdr.Set(HeaderIdempotentReplayed, "true")
+
+	w.WriteHeader(resp.StatusCode)
+	if _, err := w.Write(resp.Body); err != nil {
+		return fmt.Errorf("write fail: %w", err)
+	}
+
+	return nil
+}
+
+// captureWriter records the response written by the handler.
+type captureWriter struct {
+	http.ResponseWriter
+	statusCode int
+	header     http.Header
+	body       bytes.Buffer
+	overflow   bool
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (cw *captureWriter) WriteHeader(statusCode int) {
+	if cw.statusCode == 0 && statusCode >= http.StatusOK {
+		cw.statusCode = statusCode
+		cw.header = cw.Header().Clone()
+
+		// These headers belong to the first request only.
+		cw.header.Del(rest.HeaderRequestID)
+		cw.header.Del("Date")
+	}
+
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if cw.statusCode == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.overflow {
+		if cw.body.Len()+len(b) > maxIdempotencyBody {
+			cw.overflow = true
+			cw.body.Reset()
+		} else {
+			cw.body.Write(b)
+		}
+	}
+
+	return cw.ResponseWriter.Write(b)
+}
+
+// FlushError flushes the response, used by http.ResponseController.
+func (cw *captureWriter) FlushError() error {
+	if cw.statusCode == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	return http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Flush implements the http.Flusher interface.
+func (cw *captureWriter) Flush() {
+	_ = cw.FlushError()
+}
+
+// Unwrap returns the underlying response writer for http.ResponseController.
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore keeping the records in
+// memory.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	records   map[string]memoryIdempotencyRecord
+	nextSweep time.Time
+	now       func() time.Time
+}
+
+// memoryIdempotencyRecord is a record with its expiration time.
+type memoryIdempotencyRecord struct {
+	rec     IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore creates an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]memoryIdempotencyRecord),
+		now:     time.Now,
+	}
+}
+
+// Reserve implements the IdempotencyStore interface.
+func (s *MemoryIdempotencyStore) Reserve(_ context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.records[key]; ok && now.Before(e.expires) {
+		return e.rec, false, nil
+	}
+
+	s.records[key] = memoryIdempotencyRecord{rec: rec, expires: now.Add(ttl)}
+
+	return rec, true, nil
+}
+
+// Save implements the IdempotencyStore interface.
+func (s *MemoryIdempotencyStore) Save(_ context.Context, key string, rec IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[key] = memoryIdempotencyRecord{rec: rec, expires: s.now().Add(ttl)}
+
+	return nil
+}
+
+// Delete implements the IdempotencyStore interface.
+func (s *MemoryIdempotencyStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+
+	return nil
+}
+
+// sweep evicts the expired records, at most once a minute. It must be called
+// with the lock held.
+func (s *MemoryIdempotencyStore) sweep(now time.Time) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	s.nextSweep = now.Add(time.Minute)
+
+	for key, e := range s.records {
+		if !now.Before(e.expires) {
+			delete(s.records, key)
+		}
+	}
+}
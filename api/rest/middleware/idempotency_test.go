@@ -0,0 +1,277 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+func TestIdempotency(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	api := rest.New(make(chan os.Signal, 1), Errors(), Panics(nil), Idempotency())
+	api.Handle(http.MethodPost, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		n := calls.Add(1)
+		w.Header().Set("Location", "/sites/1")
+		return rest.Respond(ctx, w, map[string]int32{"call": n}, http.StatusCreated)
+	})
+	api.Handle(http.MethodPost, "/slow", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		close(started)
+		<-release
+		return rest.Respond(ctx, w, "ok", http.StatusCreated)
+	})
+	api.Handle(http.MethodPost, "/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		calls.Add(1)
+		return errors.New("simulated handler error")
+	})
+	api.Handle(http.MethodPost, "/panic", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		if calls.Add(1) == 1 {
+			panic("simulated handler panic")
+		}
+		return rest.Respond(ctx, w, "ok", http.StatusCreated)
+	})
+	api.Handle(http.MethodPost, "/stream", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/plain")
+		if _, err := w.Write([]byte("first ")); err != nil {
+			return err
+		}
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			return err
+		}
+		_, err := w.Write([]byte("second"))
+		return err
+	})
+
+	serve := func(target string, key string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Replay", func(t *testing.T) {
+		calls.Store(0)
+
+		first := serve("/sites", "key-1", `{"name":"site"}`)
+		second := serve("/sites", "key-1", `{"name":"site"}`)
+
+		if calls.Load() != 1 {
+			t.Errorf("Expected the handler to be called once, called %d times", calls.Load())
+		}
+		if second.Code != http.StatusCreated || second.Body.String() != first.Body.String() {
+			t.Errorf("Expected the response to be replayed, got %d %s", second.Code, second.Body.String())
+		}
+		if second.Header().Get("Location") != "/sites/1" || second.Header().Get(HeaderIdempotentReplayed) != "true" {
+			t.Errorf("Expected the headers to be replayed, got %v", second.Header())
+		}
+		if first.Header().Get(HeaderIdempotentReplayed) != "" {
+			t.Error("Expected the first response not to be flagged as replayed")
+		}
+		if second.Header().Get(rest.HeaderRequestID) == first.Header().Get(rest.HeaderRequestID) {
+			t.Error("Expected the replayed response to carry its own request ID")
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		serve("/sites", "key-2", `{"name":"site"}`)
+
+		rr := serve("/sites", "key-2", `{"name":"other"}`)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnprocessableEntity)
+		}
+	})
+
+	t.Run("Conflict", func(t *testing.T) {
+		done := make(chan *httptest.ResponseRecorder)
+		go func() {
+			done <- serve("/slow", "key-3", "")
+		}()
+
+		// Wait for the first request to hold the key.
+		<-started
+
+		rr := serve("/slow", "key-3", "")
+		if rr.Code != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+		}
+
+		close(release)
+		if first := <-done; first.Code != http.StatusCreated {
+			t.Errorf("handler returned wrong status code: got %v want %v", first.Code, http.StatusCreated)
+		}
+	})
+
+	t.Run("Failure Not Captured", func(t *testing.T) {
+		calls.Store(0)
+
+		serve("/fail", "key-4", "")
+		rr := serve("/fail", "key-4", "")
+
+		if rr.Code != http.StatusInternalServerError || calls.Load() != 2 {
+			t.Errorf("Expected the failed request to be retried, got %d after %d calls", rr.Code, calls.Load())
+		}
+	})
+
+	t.Run("Panic Not Captured", func(t *testing.T) {
+		calls.Store(0)
+
+		first := serve("/panic", "key-5", "")
+		second := serve("/panic", "key-5", "")
+
+		if first.Code != http.StatusInternalServerError || second.Code != http.StatusCreated || calls.Load() != 2 {
+			t.Errorf("Expected the request to be retried after the panic, got %d then %d after %d calls", first.Code, second.Code, calls.Load())
+		}
+	})
+
+	t.Run("Flushed", func(t *testing.T) {
+		calls.Store(0)
+
+		first := serve("/stream", "key-6", "")
+		if !first.Flushed {
+			t.Error("Expected the response to be flushed")
+		}
+
+		second := serve("/stream", "key-6", "")
+		if calls.Load() != 1 || second.Code != http.StatusOK || second.Body.String() != "first second" {
+			t.Errorf("Expected the flushed response to be replayed, got %d %q after %d calls", second.Code, second.Body.String(), calls.Load())
+		}
+	})
+
+	t.Run("Without Key", func(t *testing.T) {
+		calls.Store(0)
+
+		serve("/sites", "", "")
+		serve("/sites", "", "")
+
+		if calls.Load() != 2 {
+			t.Errorf("Expected the handler to be called twice, called %d times", calls.Load())
+		}
+	})
+}
+
+// ttlStore is an IdempotencyStore recording the TTLs it is given.
+type ttlStore struct {
+	*MemoryIdempotencyStore
+	reserveTTL time.Duration
+	saveTTL    time.Duration
+}
+
+// Reserve implements the IdempotencyStore interface.
+func (s *ttlStore) Reserve(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) (IdempotencyRecord, bool, error) {
+	s.reserveTTL = ttl
+	return s.MemoryIdempotencyStore.Reserve(ctx, key, rec, ttl)
+}
+
+// Save implements the IdempotencyStore interface.
+func (s *ttlStore) Save(ctx context.Context, key string, rec IdempotencyRecord, ttl time.Duration) error {
+	s.saveTTL = ttl
+	return s.MemoryIdempotencyStore.Save(ctx, key, rec, ttl)
+}
+
+func TestIdempotencyTTL(t *testing.T) {
+	store := &ttlStore{MemoryIdempotencyStore: NewMemoryIdempotencyStore()}
+
+	api := rest.New(make(chan os.Signal, 1), Errors(), Idempotency(
+		WithIdempotencyStore(store),
+		WithIdempotencyTTL(time.Hour),
+		WithIdempotencyLockTTL(10*time.Second),
+	))
+	api.Handle(http.MethodPost, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, "ok", http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/sites", nil)
+	req.Header.Set(HeaderIdempotencyKey, "key")
+	api.ServeHTTP(httptest.NewRecorder(), req)
+
+	if store.reserveTTL != 10*time.Second || store.saveTTL != time.Hour {
+		t.Errorf("Expected the key to be held for 10s and the response kept for 1h, got %v and %v", store.reserveTTL, store.saveTTL)
+	}
+}
+
+func TestIdempotencyMaxBodyBytes(t *testing.T) {
+	const maxBodyBytes = 4 << 20
+
+	upload := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		if err != nil {
+			return err
+		}
+		return rest.Respond(ctx, w, len(body), http.StatusCreated)
+	}
+
+	body := bytes.Repeat([]byte("a"), 2<<20)
+
+	testCases := []struct {
+		name       string
+		opts       []IdempotencyOption
+		statusCode int
+	}{
+		{name: "Default Limit", statusCode: http.StatusRequestEntityTooLarge},
+		{name: "Raised Limit", opts: []IdempotencyOption{WithIdempotencyMaxBodyBytes(maxBodyBytes)}, statusCode: http.StatusCreated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := rest.New(make(chan os.Signal, 1), Errors(), Idempotency(tc.opts...))
+			api.Handle(http.MethodPost, "/uploads", upload)
+
+			req := httptest.NewRequest(http.MethodPost, "/uploads", bytes.NewReader(body))
+			req.Header.Set(HeaderIdempotencyKey, "key")
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+
+			if rr.Code != tc.statusCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tc.statusCode)
+			}
+			if tc.statusCode == http.StatusCreated && !bytes.Contains(rr.Body.Bytes(), []byte("2097152")) {
+				t.Errorf("Expected the whole body to reach the handler, got %s", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryIdempotencyStore()
+	s.now = func() time.Time { return now }
+
+	ctx := context.Background()
+
+	if _, ok, _ := s.Reserve(ctx, "key", IdempotencyRecord{Fingerprint: "a"}, time.Minute); !ok {
+		t.Fatal("Expected the key to be reserved")
+	}
+
+	rec, ok, _ := s.Reserve(ctx, "key", IdempotencyRecord{Fingerprint: "b"}, time.Minute)
+	if ok || rec.Fingerprint != "a" {
+		t.Fatalf("Expected the existing record, got %+v", rec)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := s.Reserve(ctx, "key", IdempotencyRecord{Fingerprint: "b"}, time.Minute); !ok {
+		t.Error("Expected the expired key to be reserved again")
+	}
+
+	_ = s.Delete(ctx, "key")
+	if _, ok, _ := s.Reserve(ctx, "key", IdempotencyRecord{Fingerprint: "c"}, time.Minute); !ok {
+		t.Error("Expected the deleted key to be reserved again")
+	}
+}
@@ -0,0 +1,364 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Default settings of a JWKS loaded from a URL.
+const (
+	defaultJWKSCacheTTL      = time.Hour
+	defaultJWKSRefreshPeriod = time.Minute
+	defaultJWKSFetchTimeout  = 10 * time.Second
+	defaultJWKSRetryPeriod   = 5 * time.Second
+	maxJWKSBytes             = 1 << 20
+)
+
+// jsonWebKey is a key of a JSON Web Key Set, RFC 7517.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	// RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC keys.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	// Symmetric keys.
+	K string `json:"k"`
+}
+
+// jwksKey is a parsed key of the set.
+type jwksKey struct {
+	alg string
+	key interface{}
+}
+
+// JWKSOption configures a JWKS loaded from a URL.
+type JWKSOption interface {
+	apply(*JWKS)
+}
+
+type jwksOptionFunc func(*JWKS)
+
+func (f jwksOptionFunc) apply(j *JWKS) { f(j) }
+
+// WithJWKSClient sets the HTTP client fetching the key set,
+// http.DefaultClient by default.
+func WithJWKSClient(c *http.Client) JWKSOption {
+	return jwksOptionFunc(func(j *JWKS) {
+		if c != nil {
+			j.client = c
+		}
+	})
+}
+
+// WithJWKSCacheTTL sets how long the key set is cached, one hour by default.
+func WithJWKSCacheTTL(d time.Duration) JWKSOption {
+	return jwksOptionFunc(func(j *JWKS) {
+		if d > 0 {
+			j.ttl = d
+		}
+	})
+}
+
+// JWKS is a KeySet holding the keys of a JSON Web Key Set. Only the RSA,
+// EC P-256 and symmetric keys intended for signatures are used.
+type JWKS struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+
+	// refresh serializes the fetches of the key set, it holds a token while
+	// a fetch is in progress.
+	refresh chan struct{}
+
+	mu        sync.RWMutex
+	keys      map[string]jwksKey
+	fetchedAt time.Time
+	// failedAt is the time of the last failed fetch, which failed with err.
+	failedAt time.Time
+	err      error
+}
+
+// NewJWKSFile creates a KeySet from the JSON Web Key Set stored in the file.
+func NewJWKSFile(path string) (*JWKS, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks fail: %w", err)
+	}
+
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWKS{keys: keys}, nil
+}
+
+// NewJWKSURL creates a KeySet from the JSON Web Key Set served at the URL.
+// The key set is fetched on first use and cached. It is fetched again once
+// the cache expired or when a token is signed by an unknown key, at most
+// once a minute in that case so the key rotations are picked up. A failed
+// fetch is retried after 5 seconds, the requests failing with its error
+// meanwhile.
+func NewJWKSURL(url string, opts ...JWKSOption) *JWKS {
+	j := &JWKS{
+		url:     url,
+		client:  http.DefaultClient,
+		ttl:     defaultJWKSCacheTTL,
+		refresh: make(chan struct{}, 1),
+	}
+
+	for _, opt := range opts {
+		opt.apply(j)
+	}
+
+	return j
+}
+
+// Key implements the KeySet interface.
+func (j *JWKS) Key(ctx context.Context, kid string, alg string) (interface{}, error) {
+	keys, fetchedAt := j.cached()
+
+	if j.url != "" {
+		expired := time.Since(fetchedAt) > j.ttl
+		_, known := lookupJWKS(keys, kid, alg)
+
+		if expired || (!known && time.Since(fetchedAt) > defaultJWKSRefreshPeriod) {
+			var err error
+			keys, err = j.fetch(ctx, fetchedAt)
+			if err != nil && keys == nil {
+				return nil, err
+			}
+		}
+	}
+
+	key, ok := lookupJWKS(keys, kid, alg)
+	if !ok {
+		return nil, errKeyNotFound
+	}
+
+	return key, nil
+}
+
+// cached returns the keys in cache and when they were fetched.
+func (j *JWKS) cached() (map[string]jwksKey, time.Time) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.keys, j.fetchedAt
+}
+
+// fetch fetches the key set, unless another call did it since the keys
+// fetched at the given time were read or the last fetch failed recently. The
+// keys in cache are kept when the fetch fails. The request gives up waiting
+// for the fetch in progress when its context is done.
+func (j *JWKS) fetch(ctx context.Context, fetchedAt time.Time) (map[string]jwksKey, error) {
+	// The fetch is started whenever none is in progress, as it outlives the
+	// request.
+	select {
+	case j.refresh <- struct{}{}:
+	default:
+		select {
+		case j.refresh <- struct{}{}:
+		case <-ctx.Done():
+			keys, _ := j.cached()
+			return keys, ctx.Err()
+		}
+	}
+	defer func() { <-j.refresh }()
+
+	j.mu.RLock()
+	keys, at, failedAt, lastErr := j.keys, j.fetchedAt, j.failedAt, j.err
+	j.mu.RUnlock()
+
+	if at.After(fetchedAt) {
+		return keys, nil
+	}
+
+	// A failing server is not called again right away, the requests which
+	// waited for the failed fetch fail at once.
+	if time.Since(failedAt) < defaultJWKSRetryPeriod {
+		return keys, lastErr
+	}
+
+	// The key set is shared by the requests waiting for it, so the fetch is
+	// not canceled with the request triggering it.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultJWKSFetchTimeout)
+	defer cancel()
+
+	keys, err := j.get(ctx)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err != nil {
+		j.failedAt, j.err = time.Now(), err
+
+		// The time is recorded when keys are cached, so a failing server is
+		// not called for every request while they are used.
+		if j.keys != nil {
+			j.fetchedAt = j.failedAt
+		}
+
+		return j.keys, err
+	}
+	j.keys = keys
+	j.fetchedAt = time.Now()
+
+	return keys, nil
+}
+
+// get fetches and parses the key set.
+func (j *JWKS) get(ctx context.Context) (map[string]jwksKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("jwks request fail: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwks fetch fail: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks fetch fail: status %d", resp.StatusCode)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes))
+	if err != nil {
+		return nil, fmt.Errorf("jwks read fail: %w", err)
+	}
+
+	return parseJWKS(b)
+}
+
+// lookupJWKS returns the key of the ID for the algorithm. A token without key
+// ID is verified with the only key of the set, if so.
+func lookupJWKS(keys map[string]jwksKey, kid string, alg string) (interface{}, bool) {
+	k, ok := keys[kid]
+	if !ok && kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			k, ok = key, true
+		}
+	}
+
+	if !ok || k.alg != alg {
+		return nil, false
+	}
+
+	return k.key, true
+}
+
+// parseJWKS parses the keys of a JSON Web Key Set. The keys which are not
+// supported are skipped.
+func parseJWKS(b []byte) (map[string]jwksKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, fmt.Errorf("jwks unmarshal fail: %w", err)
+	}
+
+	keys := make(map[string]jwksKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		k, err := jwk.parse()
+		if err != nil {
+			continue
+		}
+
+		// The algorithm is optional in the key set, it must match the key
+		// type when present.
+		if jwk.Alg != "" && jwk.Alg != k.alg {
+			continue
+		}
+
+		keys[jwk.Kid] = k
+	}
+
+	return keys, nil
+}
+
+// parse returns the key of the JWK with the algorithm it is used for.
+func (jwk jsonWebKey) parse() (jwksKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return jwksKey{}, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return jwksKey{}, fmt.Errorf("invalid rsa exponent")
+		}
+
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+
+		return jwksKey{alg: AlgRS256, key: key}, nil
+
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return jwksKey{}, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != 32 {
+			return jwksKey{}, fmt.Errorf("invalid ec point")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil || len(y) != 32 {
+			return jwksKey{}, fmt.Errorf("invalid ec point")
+		}
+
+		// Reject the points which are not on the curve.
+		point := append(append([]byte{4}, x...), y...)
+		if _, err := ecdh.P256().NewPublicKey(point); err != nil {
+			return jwksKey{}, err
+		}
+
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+
+		return jwksKey{alg: AlgES256, key: key}, nil
+
+	case "oct":
+		k, err := base64.RawURLEncoding.DecodeString(jwk.K)
+		if err != nil || len(k) == 0 {
+			return jwksKey{}, fmt.Errorf("invalid symmetric key")
+		}
+
+		return jwksKey{alg: AlgHS256, key: k}, nil
+
+	default:
+		return jwksKey{}, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
@@ -0,0 +1,284 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"alg": "RS256",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func marshalJWKS(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+
+	b, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestJWKSFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, marshalJWKS(t, rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := NewJWKSFile(path)
+	if err != nil {
+		t.Fatalf("NewJWKSFile failed: %v", err)
+	}
+
+	v := NewJWTVerifier(keys)
+
+	for name, token := range map[string]string{
+		"RS256": signToken(t, jwt.SigningMethodRS256, rsaKey, "rsa-1", jwt.MapClaims{"sub": "user-1"}),
+		"ES256": signToken(t, jwt.SigningMethodES256, ecKey, "ec-1", jwt.MapClaims{"sub": "user-1"}),
+	} {
+		if _, err := v.Verify(bearerRequest(token)); err != nil {
+			t.Errorf("%s: Verify failed: %v", name, err)
+		}
+	}
+
+	// The key ID selects the key and its algorithm.
+	token := signToken(t, jwt.SigningMethodRS256, rsaKey, "ec-1", jwt.MapClaims{"sub": "user-1"})
+	if _, err := v.Verify(bearerRequest(token)); err == nil {
+		t.Error("Expected the token signed with another key to be rejected")
+	}
+}
+
+func TestJWKSURL(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var mu sync.Mutex
+	var fetches atomic.Int32
+	body := marshalJWKS(t, rsaJWK("old", &oldKey.PublicKey))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	keys := NewJWKSURL(srv.URL, WithJWKSClient(srv.Client()))
+	v := NewJWTVerifier(keys)
+
+	token := signToken(t, jwt.SigningMethodRS256, oldKey, "old", jwt.MapClaims{"sub": "user-1"})
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(bearerRequest(token)); err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+	}
+
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("Expected the key set to be cached, fetched %d times", n)
+	}
+
+	// The key set is rotated: a token signed by the new key is rejected until
+	// the next refresh, which is rate limited.
+	mu.Lock()
+	body = marshalJWKS(t, rsaJWK("old", &oldKey.PublicKey), rsaJWK("new", &newKey.PublicKey))
+	mu.Unlock()
+
+	token = signToken(t, jwt.SigningMethodRS256, newKey, "new", jwt.MapClaims{"sub": "user-1"})
+	if _, err := v.Verify(bearerRequest(token)); err == nil {
+		t.Error("Expected the unknown key to be rejected before the refresh period")
+	}
+
+	keys.mu.Lock()
+	keys.fetchedAt = keys.fetchedAt.Add(-defaultJWKSRefreshPeriod)
+	keys.mu.Unlock()
+
+	if _, err := v.Verify(bearerRequest(token)); err != nil {
+		t.Errorf("Expected the key set to be refreshed for an unknown key: %v", err)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("Expected the key set to be fetched twice, fetched %d times", n)
+	}
+}
+
+func TestJWKSURLFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	v := NewJWTVerifier(NewJWKSURL(srv.URL))
+
+	token := signToken(t, jwt.SigningMethodRS256, key, "kid", jwt.MapClaims{"sub": "user-1"})
+	if _, err := v.Verify(bearerRequest(token)); err == nil {
+		t.Error("Expected the token to be rejected")
+	}
+}
+
+func TestJWKSURLRecovery(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	body := marshalJWKS(t, rsaJWK("kid", &key.PublicKey))
+
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	keys := NewJWKSURL(srv.URL, WithJWKSClient(srv.Client()))
+	v := NewJWTVerifier(keys)
+	token := signToken(t, jwt.SigningMethodRS256, key, "kid", jwt.MapClaims{"sub": "user-1"})
+
+	if _, err := v.Verify(bearerRequest(token)); err == nil {
+		t.Fatal("Expected the token to be rejected while the key set is unavailable")
+	}
+
+	// The failure is kept for the retry period without calling the server.
+	if _, err := v.Verify(bearerRequest(token)); err == nil {
+		t.Fatal("Expected the token to be rejected during the retry period")
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Fatalf("Expected the key set to be fetched once, fetched %d times", n)
+	}
+
+	keys.mu.Lock()
+	keys.failedAt = keys.failedAt.Add(-defaultJWKSRetryPeriod)
+	keys.mu.Unlock()
+
+	// The key set is fetched again after the retry period, and the fetch
+	// outlives the request.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := v.Verify(bearerRequest(token).WithContext(ctx)); err != nil {
+		t.Errorf("Expected the key set to be fetched on the next request: %v", err)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("Expected the key set to be fetched twice, fetched %d times", n)
+	}
+}
+
+func TestJWKSURLConcurrentFailure(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	v := NewJWTVerifier(NewJWKSURL(srv.URL, WithJWKSClient(srv.Client())))
+	token := signToken(t, jwt.SigningMethodRS256, key, "kid", jwt.MapClaims{"sub": "user-1"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := v.Verify(bearerRequest(token)); err == nil {
+				t.Error("Expected the token to be rejected while the key set is unavailable")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The requests waiting for the failed fetch share its error.
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("Expected the key set to be fetched once, fetched %d times", n)
+	}
+}
+
+func TestJWKSURLWaitCancel(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	body := marshalJWKS(t, rsaJWK("kid", &key.PublicKey))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	v := NewJWTVerifier(NewJWKSURL(srv.URL, WithJWKSClient(srv.Client())))
+	token := signToken(t, jwt.SigningMethodRS256, key, "kid", jwt.MapClaims{"sub": "user-1"})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(bearerRequest(token))
+		done <- err
+	}()
+	<-started
+
+	// A request waiting for the fetch in progress gives up when it is
+	// cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	waited := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(bearerRequest(token).WithContext(ctx))
+		waited <- err
+	}()
+
+	select {
+	case err := <-waited:
+		if err == nil {
+			t.Error("Expected the cancelled request to be rejected")
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the cancelled request to stop waiting for the fetch")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the token to be verified once the key set is fetched: %v", err)
+	}
+}
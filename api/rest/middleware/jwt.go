@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	// Third party packages
+	"github.com/golang-jwt/jwt/v5"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// Signing algorithms supported for the JWT.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// errKeyNotFound is returned when no key verifies a token.
+var errKeyNotFound = errors.New("key not found")
+
+// KeySet provides the keys verifying the signature of the JWT.
+type KeySet interface {
+	// Key returns the key verifying the tokens signed with the algorithm by
+	// the key ID, which can be empty. The key type must match the algorithm:
+	// []byte for HS256, *rsa.PublicKey for RS256 and *ecdsa.PublicKey for
+	// ES256.
+	Key(ctx context.Context, kid string, alg string) (interface{}, error)
+}
+
+// staticKey is a KeySet made of a single key.
+type staticKey struct {
+	alg string
+	key interface{}
+}
+
+// HMACSecret returns the KeySet verifying the tokens signed with HS256 and
+// the given secret.
+func HMACSecret(secret []byte) KeySet {
+	return staticKey{alg: AlgHS256, key: secret}
+}
+
+// PublicKey returns the KeySet verifying the tokens signed with the private
+// key of the given public key, RS256 for an RSA key and ES256 for an ECDSA
+// P-256 key.
+func PublicKey(key crypto.PublicKey) (KeySet, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return staticKey{alg: AlgRS256, key: k}, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported curve %s", k.Curve.Params().Name)
+		}
+		return staticKey{alg: AlgES256, key: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// Key implements the KeySet interface.
+func (sk staticKey) Key(_ context.Context, _ string, alg string) (interface{}, error) {
+	// The algorithm of the token must match the key to prevent the algorithm
+	// confusion attacks.
+	if alg != sk.alg {
+		return nil, errKeyNotFound
+	}
+
+	return sk.key, nil
+}
+
+// JWTOption configures the JWTVerifier.
+type JWTOption interface {
+	apply(*JWTVerifier)
+}
+
+type jwtOptionFunc func(*JWTVerifier)
+
+func (f jwtOptionFunc) apply(v *JWTVerifier) { f(v) }
+
+// WithIssuer requires the tokens to be issued by the given issuer.
+func WithIssuer(iss string) JWTOption {
+	return jwtOptionFunc(func(v *JWTVerifier) {
+		v.parserOpts = append(v.parserOpts, jwt.WithIssuer(iss))
+	})
+}
+
+// WithAudience requires the tokens to be issued for the given audience.
+func WithAudience(aud string) JWTOption {
+	return jwtOptionFunc(func(v *JWTVerifier) {
+		v.parserOpts = append(v.parserOpts, jwt.WithAudience(aud))
+	})
+}
+
+// WithLeeway sets the clock skew tolerated when checking the expiration and
+// not before times.
+func WithLeeway(d time.Duration) JWTOption {
+	return jwtOptionFunc(func(v *JWTVerifier) {
+		v.parserOpts = append(v.parserOpts, jwt.WithLeeway(d))
+	})
+}
+
+// WithScopeClaim sets the claim holding the scopes of the principal, "scope"
+// by default. The claim is either a space separated string or an array.
+func WithScopeClaim(name string) JWTOption {
+	return jwtOptionFunc(func(v *JWTVerifier) {
+		v.scopeClaim = name
+	})
+}
+
+// WithRolesClaim sets the claim holding the roles of the principal, "roles"
+// by default. The claim is either a space separated string or an array.
+func WithRolesClaim(name string) JWTOption {
+	return jwtOptionFunc(func(v *JWTVerifier) {
+		v.rolesClaim = name
+	})
+}
+
+// JWTVerifier authenticates the requests carrying a bearer JWT signed with
+// HS256, RS256 or ES256. The expiration time is required.
+type JWTVerifier struct {
+	keys       KeySet
+	parserOpts []jwt.ParserOption
+	scopeClaim string
+	rolesClaim string
+}
+
+// NewJWTVerifier creates a Verifier for the bearer tokens signed with the
+// keys of the KeySet.
+func NewJWTVerifier(keys KeySet, opts ...JWTOption) *JWTVerifier {
+	v := &JWTVerifier{
+		keys: keys,
+		parserOpts: []jwt.ParserOption{
+			jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgES256}),
+			jwt.WithExpirationRequired(),
+		},
+		scopeClaim: "scope",
+		rolesClaim: "roles",
+	}
+
+	for _, opt := range opts {
+		opt.apply(v)
+	}
+
+	return v
+}
+
+// Challenge implements the Challenger interface.
+func (v *JWTVerifier) Challenge() string {
+	return "Bearer"
+}
+
+// Verify implements the Verifier interface.
+func (v *JWTVerifier) Verify(r *http.Request) (*rest.Principal, error) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+
+	claims := jwt.MapClaims{}
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(r.Context(), kid, t.Method.Alg())
+	}
+
+	if _, err := jwt.ParseWithClaims(strings.TrimSpace(token), claims, keyFunc, v.parserOpts...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	sub, _ := claims.GetSubject()
+
+	p := rest.Principal{
+		Subject: sub,
+		Method:  "jwt",
+		Scopes:  claimStrings(claims[v.scopeClaim]),
+		Roles:   claimStrings(claims[v.rolesClaim]),
+		Claims:  claims,
+	}
+
+	return &p, nil
+}
+
+// claimStrings returns the values of a claim holding either a space
+// separated string or an array of strings.
+func claimStrings(claim interface{}) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(c)
+	case []interface{}:
+		values := make([]string, 0, len(c))
+		for _, v := range c {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
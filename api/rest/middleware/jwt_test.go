@@ -0,0 +1,133 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signToken returns a token signed with the key, expiring in an hour unless
+// the claims say otherwise.
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign the token: %v", err)
+	}
+
+	return s
+}
+
+func bearerRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKeys, err := PublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKeys, err := PublicKey(&ecKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":   "user-1",
+			"iss":   "https://issuer",
+			"aud":   "api",
+			"scope": "sites:read sites:write",
+			"roles": []string{"admin"},
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		keys    KeySet
+		token   string
+		wantErr bool
+	}{
+		{name: "HS256", keys: HMACSecret([]byte("secret")), token: signToken(t, jwt.SigningMethodHS256, []byte("secret"), "", claims())},
+		{name: "RS256", keys: rsaKeys, token: signToken(t, jwt.SigningMethodRS256, rsaKey, "", claims())},
+		{name: "ES256", keys: ecKeys, token: signToken(t, jwt.SigningMethodES256, ecKey, "", claims())},
+		{name: "Wrong Secret", keys: HMACSecret([]byte("secret")), token: signToken(t, jwt.SigningMethodHS256, []byte("other"), "", claims()), wantErr: true},
+		{name: "Algorithm Mismatch", keys: rsaKeys, token: signToken(t, jwt.SigningMethodES256, ecKey, "", claims()), wantErr: true},
+		{name: "Unsupported Algorithm", keys: HMACSecret([]byte("secret")), token: signToken(t, jwt.SigningMethodHS512, []byte("secret"), "", claims()), wantErr: true},
+		{name: "Expired", keys: HMACSecret([]byte("secret")), token: signToken(t, jwt.SigningMethodHS256, []byte("secret"), "", jwt.MapClaims{
+			"sub": "user-1", "iss": "https://issuer", "aud": "api", "exp": time.Now().Add(-time.Hour).Unix(),
+		}), wantErr: true},
+		{name: "Wrong Issuer", keys: HMACSecret([]byte("secret")), token: signToken(t, jwt.SigningMethodHS256, []byte("secret"), "", jwt.MapClaims{
+			"sub": "user-1", "iss": "https://other", "aud": "api",
+		}), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := NewJWTVerifier(tc.keys, WithIssuer("https://issuer"), WithAudience("api"))
+
+			p, err := v.Verify(bearerRequest(tc.token))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Expected the token to be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+
+			if p.Subject != "user-1" || p.Method != "jwt" {
+				t.Errorf("Unexpected principal: %+v", p)
+			}
+			if len(p.Scopes) != 2 || p.Scopes[1] != "sites:write" {
+				t.Errorf("Expected the scopes to be parsed, got %v", p.Scopes)
+			}
+			if len(p.Roles) != 1 || p.Roles[0] != "admin" {
+				t.Errorf("Expected the roles to be parsed, got %v", p.Roles)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierNoCredentials(t *testing.T) {
+	v := NewJWTVerifier(HMACSecret([]byte("secret")))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+
+	if _, err := v.Verify(req); err != ErrNoCredentials {
+		t.Errorf("Expected %v, got %v", ErrNoCredentials, err)
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// LoggerOption configures the Logger middleware.
+type LoggerOption interface {
+	apply(*loggerOptions)
+}
+
+// loggerOptions holds the settings of the Logger middleware.
+type loggerOptions struct {
+	// levels holds the log level keyed by status class (2 for 2xx etc).
+	levels map[int]slog.Level
+	// sampling logs only one successful request out of sampling.
+	sampling uint64
+}
+
+type loggerOptionFunc func(*loggerOptions)
+
+func (f loggerOptionFunc) apply(o *loggerOptions) { f(o) }
+
+// WithStatusLevel sets the log level used for the given status class. The
+// class is the first digit of the status code, e.g. 4 for 4xx.
+func WithStatusLevel(class int, level slog.Level) LoggerOption {
+	return loggerOptionFunc(func(o *loggerOptions) {
+		o.levels[class] = level
+	})
+}
+
+// WithSuccessSampling logs only one out of every n successful requests.
+// Failed requests are always logged.
+func WithSuccessSampling(n int) LoggerOption {
+	return loggerOptionFunc(func(o *loggerOptions) {
+		if n > 0 {
+			o.sampling = uint64(n)
+		}
+	})
+}
+
+// Logger writes information about every request to the given logger once the
+// handler chain has run. It should be registered before the Errors middleware
+// so the final status code is logged.
+func Logger(log *slog.Logger, opts ...LoggerOption) rest.Middleware {
+	o := loggerOptions{
+		levels: map[int]slog.Level{
+			2: slog.LevelInfo,
+			3: slog.LevelInfo,
+			4: slog.LevelWarn,
+			5: slog.LevelError,
+		},
+		sampling: 1,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	var successes atomic.Uint64
+
+	// This is the actual middleware function to be executed.
+	m := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			v, err := rest.GetContextValues(ctx)
+			if err != nil {
+				return err
+			}
+
+			start := time.Now()
+
+			// Run the next handler and catch any propagated error.
+			err = handler(ctx, w, r)
+
+			statusCode := finalStatusCode(v, err)
+			isError := err != nil || v.IsError
+
+			// Skip the successful requests which are not part of the sample.
+			if !isError && statusCode < http.StatusBadRequest && o.sampling > 1 {
+				if successes.Add(1)%o.sampling != 1 {
+					return err
+				}
+			}
+
+			level, ok := o.levels[statusCode/100]
+			if !ok {
+				level = slog.LevelInfo
+			}
+
+			log.LogAttrs(ctx, level, "request completed",
+				slog.String("method", r.Method),
+				slog.String("path", v.Path),
+				slog.Int("status", statusCode),
+				slog.Duration("latency", time.Since(start)),
+				slog.Int64("bytes", v.BytesWritten),
+				slog.Duration("ttfb", v.TimeToFirstByte),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.Bool("error", isError),
+				slog.String("request_id", v.RequestID),
+			)
+
+			return err
+		})
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,123 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// logEntry is the subset of a logged request used by the tests.
+type logEntry struct {
+	Level  string `json:"level"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	Bytes  int64  `json:"bytes"`
+	Error  bool   `json:"error"`
+}
+
+// newTestLoggerAPI returns an API logging into the returned buffer.
+func newTestLoggerAPI(opts ...LoggerOption) (*rest.API, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	log := slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	shutdown := make(chan os.Signal, 1)
+	api := rest.New(shutdown, Logger(log, opts...), Errors())
+
+	api.Handle(http.MethodGet, "/sites/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, "ok", http.StatusOK)
+	})
+	api.Handle(http.MethodGet, "/missing", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.NewRequestError(errors.New("not found"), http.StatusNotFound, "not_found")
+	})
+	api.Handle(http.MethodGet, "/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errors.New("simulated handler error")
+	})
+
+	return api, buf
+}
+
+// readLogEntries decodes every line in the buffer.
+func readLogEntries(t *testing.T, buf *bytes.Buffer) []logEntry {
+	t.Helper()
+
+	var entries []logEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var e logEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("could not unmarshal log line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+
+	return entries
+}
+
+func TestLoggerMiddleware(t *testing.T) {
+	t.Run("Levels", func(t *testing.T) {
+		api, buf := newTestLoggerAPI(WithStatusLevel(4, slog.LevelDebug))
+
+		for _, target := range []string{"/sites/1", "/missing", "/fail"} {
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		}
+
+		entries := readLogEntries(t, buf)
+		if len(entries) != 3 {
+			t.Fatalf("Expected 3 log entries, got %d", len(entries))
+		}
+
+		expected := []logEntry{
+			{Level: "INFO", Method: "GET", Path: "/sites/{id}", Status: http.StatusOK},
+			{Level: "DEBUG", Method: "GET", Path: "/missing", Status: http.StatusNotFound, Error: true},
+			{Level: "ERROR", Method: "GET", Path: "/fail", Status: http.StatusInternalServerError, Error: true},
+		}
+		for i, e := range expected {
+			got := entries[i]
+			if got.Bytes == 0 {
+				t.Errorf("Expected bytes written to be logged: %+v", got)
+			}
+			got.Bytes = 0
+			if got != e {
+				t.Errorf("Unexpected log entry: got %+v want %+v", got, e)
+			}
+		}
+	})
+
+	t.Run("Success Sampling", func(t *testing.T) {
+		api, buf := newTestLoggerAPI(WithSuccessSampling(2))
+
+		for _, target := range []string{"/sites/1", "/sites/2", "/sites/3", "/sites/4", "/fail", "/fail"} {
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		}
+
+		var successes, failures int
+		for _, e := range readLogEntries(t, buf) {
+			if e.Error {
+				failures++
+			} else {
+				successes++
+			}
+		}
+		if successes != 2 || failures != 2 {
+			t.Errorf("Expected 2 successes and 2 failures to be logged, got %d and %d", successes, failures)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"errors"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// Settings of the MemoryStore.
+const (
+	memoryStoreShards = 32
+	memoryStoreSweep  = time.Minute
+)
+
+// errInvalidLimit is returned for a limit without requests or period.
+var errInvalidLimit = errors.New("invalid limit: requests and period must be positive")
+
+// MemoryStore is a Store keeping the rate limits in memory. The keys are
+// spread over shards to reduce the lock contention, and the keys idle for a
+// whole period are evicted.
+type MemoryStore struct {
+	shards [memoryStoreShards]memoryShard
+	now    func() time.Time
+}
+
+// memoryShard holds the state of a part of the keys.
+type memoryShard struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	nextSweep time.Time
+}
+
+// memoryEntry is the state of a key.
+type memoryEntry struct {
+	// tokens and last are the state of the TokenBucket algorithm.
+	tokens float64
+	last   time.Time
+	// start, current and previous are the state of the SlidingWindow
+	// algorithm.
+	start    time.Time
+	current  int
+	previous int
+	// expires is the time the entry can be evicted.
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		now: time.Now,
+	}
+
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]*memoryEntry)
+	}
+
+	return s
+}
+
+// Allow implements the Store interface.
+func (s *MemoryStore) Allow(_ context.Context, key string, alg Algorithm, limit Limit) (Result, error) {
+	if limit.Requests < 1 || limit.Period <= 0 {
+		return Result{}, errInvalidLimit
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%memoryStoreShards]
+
+	now := s.now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.sweep(now)
+
+	e, ok := shard.entries[key]
+	if !ok {
+		e = &memoryEntry{}
+		shard.entries[key] = e
+	}
+	e.expires = now.Add(2 * limit.Period)
+
+	if alg == SlidingWindow {
+		return e.slidingWindow(now, limit), nil
+	}
+
+	return e.tokenBucket(now, limit), nil
+}
+
+// sweep evicts the expired entries, at most once a minute.
+func (sh *memoryShard) sweep(now time.Time) {
+	if now.Before(sh.nextSweep) {
+		return
+	}
+	sh.nextSweep = now.Add(memoryStoreSweep)
+
+	for key, e := range sh.entries {
+		if now.After(e.expires) {
+			delete(sh.entries, key)
+		}
+	}
+}
+
+// tokenBucket counts a request with the TokenBucket algorithm.
+func (e *memoryEntry) tokenBucket(now time.Time, limit Limit) Result {
+	capacity := float64(limit.Burst)
+	if limit.Burst <= 0 {
+		capacity = float64(limit.Requests)
+	}
+
+	// rate is the number of tokens refilled per second.
+	rate := float64(limit.Requests) / limit.Period.Seconds()
+
+	if e.last.IsZero() {
+		e.tokens = capacity
+	} else {
+		e.tokens = math.Min(capacity, e.tokens+now.Sub(e.last).Seconds()*rate)
+	}
+	e.last = now
+
+	res := Result{
+		Limit: int(capacity),
+	}
+
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsDuration((1 - e.tokens) / rate)
+	}
+
+	res.Remaining = int(e.tokens)
+	res.Reset = secondsDuration((capacity - e.tokens) / rate)
+
+	return res
+}
+
+// slidingWindow counts a request with the SlidingWindow algorithm.
+func (e *memoryEntry) slidingWindow(now time.Time, limit Limit) Result {
+	start := now.Truncate(limit.Period)
+
+	// Move to the current window.
+	if !start.Equal(e.start) {
+		if start.Sub(e.start) == limit.Period {
+			e.previous = e.current
+		} else {
+			e.previous = 0
+		}
+		e.current = 0
+		e.start = start
+	}
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(limit.Period)
+	count := float64(e.previous)*weight + float64(e.current)
+
+	res := Result{
+		Limit: limit.Requests,
+		Reset: start.Add(limit.Period).Sub(now),
+	}
+
+	if count+1 <= float64(limit.Requests) {
+		e.current++
+		res.Allowed = true
+		res.Remaining = int(float64(limit.Requests) - count - 1)
+		return res
+	}
+
+	// The request can be retried once the weight of the previous window is
+	// low enough, or in the next window when the current one is full.
+	res.RetryAfter = res.Reset
+	if e.current+1 <= limit.Requests && e.previous > 0 {
+		w := float64(limit.Requests-e.current-1) / float64(e.previous)
+		at := time.Duration((1 - w) * float64(limit.Period))
+		res.RetryAfter = at - elapsed
+	}
+
+	return res
+}
+
+// secondsDuration returns the duration of the given seconds.
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,140 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestMemoryStore returns a store whose clock is moved by the returned
+// function.
+func newTestMemoryStore() (*MemoryStore, func(d time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestMemoryStoreTokenBucket(t *testing.T) {
+	s, advance := newTestMemoryStore()
+	limit := Limit{Requests: 10, Period: 10 * time.Second, Burst: 3}
+
+	allow := func() Result {
+		res, err := s.Allow(context.Background(), "key", TokenBucket, limit)
+		if err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+		return res
+	}
+
+	// The burst is allowed right away.
+	for i := 2; i >= 0; i-- {
+		res := allow()
+		if !res.Allowed || res.Remaining != i || res.Limit != 3 {
+			t.Fatalf("Expected the request to be allowed with %d remaining, got %+v", i, res)
+		}
+	}
+
+	res := allow()
+	if res.Allowed || res.RetryAfter != time.Second {
+		t.Fatalf("Expected the request to be denied for a second, got %+v", res)
+	}
+
+	// A token is refilled every second.
+	advance(time.Second)
+	if res := allow(); !res.Allowed {
+		t.Errorf("Expected the refilled token to be used, got %+v", res)
+	}
+
+	advance(time.Minute)
+	if res := allow(); !res.Allowed || res.Remaining != 2 {
+		t.Errorf("Expected the bucket to be full, got %+v", res)
+	}
+
+	// Other keys have their own bucket.
+	if res, _ := s.Allow(context.Background(), "other", TokenBucket, limit); !res.Allowed || res.Remaining != 2 {
+		t.Errorf("Expected a full bucket for another key, got %+v", res)
+	}
+}
+
+func TestMemoryStoreSlidingWindow(t *testing.T) {
+	s, advance := newTestMemoryStore()
+	limit := Limit{Requests: 4, Period: 10 * time.Second}
+
+	allow := func() Result {
+		res, err := s.Allow(context.Background(), "key", SlidingWindow, limit)
+		if err != nil {
+			t.Fatalf("Allow failed: %v", err)
+		}
+		return res
+	}
+
+	for i := 3; i >= 0; i-- {
+		if res := allow(); !res.Allowed || res.Remaining != i {
+			t.Fatalf("Expected the request to be allowed with %d remaining, got %+v", i, res)
+		}
+	}
+
+	res := allow()
+	if res.Allowed || res.RetryAfter != 10*time.Second {
+		t.Fatalf("Expected the request to be denied until the next window, got %+v", res)
+	}
+
+	// Halfway through the next window, half of the previous window counts.
+	advance(15 * time.Second)
+	for i := 0; i < 2; i++ {
+		if res := allow(); !res.Allowed {
+			t.Fatalf("Expected the request %d to be allowed, got %+v", i, res)
+		}
+	}
+
+	res = allow()
+	if res.Allowed {
+		t.Fatalf("Expected the request to be denied, got %+v", res)
+	}
+	// The previous window weighs less than one request 2.5s later.
+	if res.RetryAfter != 2500*time.Millisecond {
+		t.Errorf("Expected to retry after 2.5s, got %v", res.RetryAfter)
+	}
+
+	advance(res.RetryAfter)
+	if res := allow(); !res.Allowed {
+		t.Errorf("Expected the request to be allowed after the retry delay, got %+v", res)
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	s, advance := newTestMemoryStore()
+	limit := Limit{Requests: 1, Period: time.Second}
+
+	for _, key := range []string{"a", "b", "c"} {
+		_, _ = s.Allow(context.Background(), key, TokenBucket, limit)
+	}
+
+	// The first key is used again before being evicted.
+	advance(time.Minute)
+	_, _ = s.Allow(context.Background(), "a", TokenBucket, limit)
+	advance(time.Second)
+
+	count := 0
+	for i := range s.shards {
+		s.shards[i].sweep(s.now())
+		count += len(s.shards[i].entries)
+	}
+	if count != 1 {
+		t.Errorf("Expected the idle keys to be evicted, %d keys left", count)
+	}
+}
+
+func TestMemoryStoreInvalidLimit(t *testing.T) {
+	s := NewMemoryStore()
+
+	if _, err := s.Allow(context.Background(), "key", TokenBucket, Limit{Requests: 10}); err == nil {
+		t.Error("Expected an error for a limit without period")
+	}
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+	"github.com/coderkakarrot/go-pkg-lib/metric"
+)
+
+// Metrics records the request count, latency, in-flight requests and error
+// count of each request. The route pattern registered in the API is used as
+// the path label to keep the cardinality bounded. It should be registered
+// before the Errors middleware so the final status code is recorded.
+func Metrics(m *metric.Metric) rest.Middleware {
+	// The gauge is created here as the released metric package does not
+	// provide it yet. The instrument is the same as metric.Metric.InFlight,
+	// which replaces it once released.
+	inFlight, _ := m.NewGauge("in_flight_request", "Gauge of all requests being served")
+
+	// This is the actual middleware function to be executed.
+	mw := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			v, err := rest.GetContextValues(ctx)
+			if err != nil {
+				return err
+			}
+
+			opt := metric.Attributes{
+				"method": r.Method,
+				"path":   v.Path,
+			}
+
+			if inFlight != nil {
+				inFlight.Add(ctx, 1, opt)
+			}
+			start := time.Now()
+
+			// Run the next handler and catch any propagated error.
+			err = handler(ctx, w, r)
+
+			elapsed := time.Since(start)
+			if inFlight != nil {
+				inFlight.Add(ctx, -1, opt)
+			}
+
+			statusCode := finalStatusCode(v, err)
+			opt = metric.Attributes{
+				"method": r.Method,
+				"path":   v.Path,
+				"status": strconv.Itoa(statusCode),
+			}
+
+			m.Request.Add(ctx, 1, opt)
+			m.Latency.Record(ctx, elapsed.Seconds(), opt)
+
+			if err != nil || statusCode >= http.StatusInternalServerError {
+				m.Errors.Add(ctx, 1, opt)
+			}
+
+			return err
+		})
+
+		return h
+	}
+
+	return mw
+}
+
+// finalStatusCode returns the status code of the request once the handler
+// chain has run. A handler which wrote nothing leaves the status code unset,
+// which means the server sends an implicit 200.
+func finalStatusCode(v *rest.ContextValues, err error) int {
+	switch {
+	case v.StatusCode != 0:
+		return v.StatusCode
+	case err != nil:
+		return http.StatusInternalServerError
+	default:
+		return http.StatusOK
+	}
+}
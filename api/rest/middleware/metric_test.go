@@ -0,0 +1,80 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+	"github.com/coderkakarrot/go-pkg-lib/metric"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	m, err := metric.Initialise("testMetricsMiddleware")
+	if err != nil {
+		t.Fatalf("failed to initialize provider: %v", err)
+	}
+	defer m.Shutdown(context.Background())
+
+	shutdown := make(chan os.Signal, 1)
+	api := rest.New(shutdown, Metrics(m), Errors())
+
+	// The exporter is scraped while the request is served.
+	var during string
+	api.Handle(http.MethodGet, "/sites/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		rr := httptest.NewRecorder()
+		m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		during = rr.Body.String()
+
+		return rest.Respond(ctx, w, "ok", http.StatusOK)
+	})
+	api.Handle(http.MethodGet, "/fail", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return errors.New("simulated handler error")
+	})
+
+	for _, target := range []string{"/sites/1", "/sites/2", "/fail"} {
+		api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	// Scrape the exporter to check what has been recorded.
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rr.Body.String()
+
+	var lines []string
+	for _, line := range strings.Split(body, "\n") {
+		if strings.Contains(line, `otel_scope_name="testMetricsMiddleware"`) {
+			lines = append(lines, line)
+		}
+	}
+	scraped := strings.Join(lines, "\n")
+
+	expected := []string{
+		`request_total{method="GET",otel_scope_name="testMetricsMiddleware",otel_scope_version="",path="/sites/{id}",status="200"} 2`,
+		`request_total{method="GET",otel_scope_name="testMetricsMiddleware",otel_scope_version="",path="/fail",status="500"} 1`,
+		`error_total{method="GET",otel_scope_name="testMetricsMiddleware",otel_scope_version="",path="/fail",status="500"} 1`,
+		`in_flight_request{method="GET",otel_scope_name="testMetricsMiddleware",otel_scope_version="",path="/sites/{id}"} 0`,
+		`latency_count{method="GET",otel_scope_name="testMetricsMiddleware",otel_scope_version="",path="/sites/{id}",status="200"} 2`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(scraped, e) {
+			t.Errorf("Expected metric %q in:\n%s", e, scraped)
+		}
+	}
+
+	inFlight := `in_flight_request{method="GET",otel_scope_name="testMetricsMiddleware",otel_scope_version="",path="/sites/{id}"} 1`
+	if !strings.Contains(during, inFlight) {
+		t.Errorf("Expected metric %q while the request is served in:\n%s", inFlight, during)
+	}
+
+	if strings.Contains(scraped, `path="/sites/1"`) {
+		t.Errorf("Expected the route pattern to be used as path label:\n%s", scraped)
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+	"github.com/coderkakarrot/go-pkg-lib/metric"
+)
+
+// panicError is a recovered panic along with the stack of the goroutine which
+// panicked. It is the value re-panicked by Timeout, so Panics reports the
+// stack of the handler rather than its own.
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+// Error implements the error interface.
+func (e *panicError) Error() string {
+	return fmt.Sprintf("PANIC [%v] TRACE[%s]", e.value, e.stack)
+}
+
+// Panics recovers from panics and converts the panic to an error so it is
+// reported in Metrics and handled in Errors. The counter is optional, when it
+// is not nil it is incremented on every recovered panic.
+func Panics(counter *metric.Counter) rest.Middleware {
+	// This is the actual middleware function to be executed.
+	m := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) (err error) {
+			// Defer a function to recover from a panic and set the err return
+			// variable after the fact.
+			defer func() {
+				if rec := recover(); rec != nil {
+					pe, ok := rec.(*panicError)
+					if !ok {
+						pe = &panicError{value: rec, stack: debug.Stack()}
+					}
+					err = pe
+
+					if counter != nil {
+						path := r.URL.Path
+						if v, vErr := rest.GetContextValues(ctx); vErr == nil {
+							path = v.Path
+						}
+
+						counter.Add(ctx, 1, metric.Attributes{
+							"method": r.Method,
+							"path":   path,
+						})
+					}
+				}
+			}()
+
+			// Call the next handler and set its return value in the err variable.
+			return handler(ctx, w, r)
+		})
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,69 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+	"github.com/coderkakarrot/go-pkg-lib/metric"
+)
+
+func TestPanicsMiddleware(t *testing.T) {
+	mockHandlerWithPanic := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		panic("simulated handler panic")
+	}
+
+	t.Run("Recover", func(t *testing.T) {
+		var recovered error
+		h := Panics(nil)(mockHandlerWithPanic)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		recovered = h(context.Background(), httptest.NewRecorder(), req)
+
+		if recovered == nil {
+			t.Fatal("Expected the panic to be converted into an error")
+		}
+		if !strings.Contains(recovered.Error(), "simulated handler panic") {
+			t.Errorf("Expected panic value in error, got %q", recovered.Error())
+		}
+		if !strings.Contains(recovered.Error(), "TRACE") {
+			t.Errorf("Expected stack trace in error, got %q", recovered.Error())
+		}
+	})
+
+	t.Run("Errors Response", func(t *testing.T) {
+		m, err := metric.Initialise("testMeter")
+		if err != nil {
+			t.Fatalf("failed to initialize provider: %v", err)
+		}
+		defer m.Shutdown(context.Background())
+
+		shutdown := make(chan os.Signal, 1)
+		api := rest.New(shutdown, Errors(), Panics(m.Panics))
+		api.Handle(http.MethodGet, "/panic", mockHandlerWithPanic)
+
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				rr.Code, http.StatusInternalServerError)
+		}
+
+		response := rest.Response{}
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatal("could not unmarshal response body")
+		}
+		if response.Success || strings.Contains(rr.Body.String(), "simulated handler panic") {
+			t.Errorf("handler returned wrong response: got %v", rr.Body.String())
+		}
+	})
+}
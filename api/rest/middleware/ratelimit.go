@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	// Standard library packages
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// ErrRateLimited is the error sent when the client exceeded the rate limit.
+var ErrRateLimited = errors.New("too many requests")
+
+// Rate limit headers.
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+	HeaderRetryAfter         = "Retry-After"
+)
+
+// Algorithm is a rate limiting algorithm.
+type Algorithm int
+
+const (
+	// TokenBucket allows bursts up to the burst size, the tokens being
+	// refilled at the rate of the limit.
+	TokenBucket Algorithm = iota
+	// SlidingWindow allows the number of requests of the limit over any
+	// period, estimated from the counts of the current and previous fixed
+	// windows.
+	SlidingWindow
+)
+
+// Limit is the number of requests allowed per period.
+type Limit struct {
+	// Requests is the number of requests allowed per period.
+	Requests int
+	// Period is the period of the limit.
+	Period time.Duration
+	// Burst is the size of the bucket of the TokenBucket algorithm, Requests
+	// when zero.
+	Burst int
+}
+
+// Result is the outcome of a request against its rate limit.
+type Result struct {
+	// Allowed reports whether the request is allowed.
+	Allowed bool
+	// Limit is the number of requests allowed.
+	Limit int
+	// Remaining is the number of requests which can be made right away.
+	Remaining int
+	// Reset is the time until the quota is fully available again.
+	Reset time.Duration
+	// RetryAfter is the time until a denied request can be retried.
+	RetryAfter time.Duration
+}
+
+// Store keeps the state of the rate limits. The algorithm runs in the store
+// so a shared store, e.g. Redis, can apply it atomically.
+type Store interface {
+	// Allow counts a request for the key against the limit.
+	Allow(ctx context.Context, key string, alg Algorithm, limit Limit) (Result, error)
+}
+
+// KeyFunc returns the key a request is rate limited by. A request with an
+// empty key is not rate limited.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP rate limits the requests by client IP, read from the remote
+// address of the connection. Use a middleware setting the remote address
+// from the trusted proxy headers when the API runs behind a proxy.
+func KeyByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// KeyByPrincipal rate limits the requests by authenticated principal, and by
+// client IP for the anonymous requests.
+func KeyByPrincipal(r *http.Request) string {
+	if p, ok := rest.GetPrincipal(r.Context()); ok {
+		return "principal:" + p.Method + ":" + p.Subject
+	}
+
+	return "ip:" + KeyByIP(r)
+}
+
+// RateLimitOption configures the RateLimit middleware.
+type RateLimitOption interface {
+	apply(*rateLimitOptions)
+}
+
+// rateLimitOptions holds the settings of the RateLimit middleware.
+type rateLimitOptions struct {
+	alg    Algorithm
+	key    KeyFunc
+	store  Store
+	prefix string
+}
+
+type rateLimitOptionFunc func(*rateLimitOptions)
+
+func (f rateLimitOptionFunc) apply(o *rateLimitOptions) { f(o) }
+
+// WithAlgorithm sets the rate limiting algorithm, TokenBucket by default.
+func WithAlgorithm(alg Algorithm) RateLimitOption {
+	return rateLimitOptionFunc(func(o *rateLimitOptions) {
+		o.alg = alg
+	})
+}
+
+// WithKeyFunc sets how the requests are keyed, KeyByIP by default.
+func WithKeyFunc(fn KeyFunc) RateLimitOption {
+	return rateLimitOptionFunc(func(o *rateLimitOptions) {
+		if fn != nil {
+			o.key = fn
+		}
+	})
+}
+
+// WithStore sets the store of the rate limits. By default, every RateLimit
+// middleware keeps its own state in memory.
+func WithStore(store Store) RateLimitOption {
+	return rateLimitOptionFunc(func(o *rateLimitOptions) {
+		if store != nil {
+			o.store = store
+		}
+	})
+}
+
+// WithKeyPrefix prefixes the keys, so the limits sharing a store do not
+// share their state.
+func WithKeyPrefix(prefix string) RateLimitOption {
+	return rateLimitOptionFunc(func(o *rateLimitOptions) {
+		o.prefix = prefix
+	})
+}
+
+// RateLimit limits the rate of the requests of each client. It is registered
+// on the API for a global limit or on a route for a limit of its own. The
+// RateLimit headers are sent with every response and a request over the
+// limit is answered with a 429 and the Retry-After header.
+func RateLimit(limit Limit, opts ...RateLimitOption) rest.Middleware {
+	o := rateLimitOptions{
+		alg: TokenBucket,
+		key: KeyByIP,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	if o.store == nil {
+		o.store = NewMemoryStore()
+	}
+
+	policy := fmt.Sprintf("%d;w=%d", limit.Requests, int(math.Ceil(limit.Period.Seconds())))
+
+	// This is the actual middleware function to be executed.
+	m := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			key := o.key(r)
+			if key == "" {
+				return handler(ctx, w, r)
+			}
+
+			res, err := o.store.Allow(ctx, o.prefix+key, o.alg, limit)
+			if err != nil {
+				return fmt.Errorf("rate limit: %w", err)
+			}
+
+			hdr := w.Header()
+			hdr.Set(HeaderRateLimitLimit, strconv.Itoa(res.Limit))
+			hdr.Set(HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+			hdr.Set(HeaderRateLimitReset, strconv.Itoa(seconds(res.Reset)))
+			hdr.Set(HeaderRateLimitPolicy, policy)
+
+			if res.Allowed {
+				return handler(ctx, w, r)
+			}
+
+			hdr.Set(HeaderRetryAfter, strconv.Itoa(seconds(res.RetryAfter)))
+
+			_ = rest.SetIsError(ctx)
+			er := rest.ErrorResponse{
+				Code:    rest.CodeRateLimited,
+				Message: ErrRateLimited.Error(),
+			}
+
+			return rest.Respond(ctx, w, er, http.StatusTooManyRequests)
+		})
+
+		return h
+	}
+
+	return m
+}
+
+// seconds returns the duration in seconds, rounded up.
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,123 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+func TestRateLimit(t *testing.T) {
+	api := rest.New(make(chan os.Signal, 1), RateLimit(Limit{Requests: 2, Period: time.Minute}, WithAlgorithm(SlidingWindow)))
+	api.Handle(http.MethodGet, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, "ok", http.StatusOK)
+	})
+
+	// A route with a limit of its own on top of the global one.
+	api.Handle(http.MethodPost, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, "ok", http.StatusCreated)
+	}, RateLimit(Limit{Requests: 1, Period: time.Minute}))
+
+	serve := func(method string, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/sites", nil)
+		req.RemoteAddr = remoteAddr
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(http.MethodGet, "10.0.0.1:1234")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	expected := map[string]string{
+		HeaderRateLimitLimit:     "2",
+		HeaderRateLimitRemaining: "1",
+		HeaderRateLimitPolicy:    "2;w=60",
+	}
+	for name, value := range expected {
+		if got := rr.Header().Get(name); got != value {
+			t.Errorf("Expected %s %q, got %q", name, value, got)
+		}
+	}
+
+	rr = serve(http.MethodPost, "10.0.0.1:1234")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+
+	// The global limit is reached.
+	rr = serve(http.MethodGet, "10.0.0.1:5678")
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusTooManyRequests)
+	}
+	if rr.Header().Get(HeaderRetryAfter) == "" {
+		t.Error("Expected the Retry-After header")
+	}
+
+	var res rest.Response
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("Failed to unmarshal the response: %v", err)
+	}
+	errs, _ := res.Errors.(map[string]interface{})
+	if res.Success || errs["code"] != rest.CodeRateLimited {
+		t.Errorf("Expected a rate limited error, got %s", rr.Body.String())
+	}
+
+	// Another client has its own quota, but the route limit applies.
+	if rr := serve(http.MethodPost, "10.0.0.2:1234"); rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+	}
+	if rr := serve(http.MethodPost, "10.0.0.2:1234"); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "[2001:db8::1]:443"
+
+	if got := KeyByIP(req); got != "2001:db8::1" {
+		t.Errorf("Expected the IP of the remote address, got %q", got)
+	}
+
+	var key string
+	api := rest.New(make(chan os.Signal, 1))
+	api.Handle(http.MethodGet, "/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		key = KeyByPrincipal(r)
+		return nil
+	}, withPrincipal(&rest.Principal{Subject: "user-1", Method: "jwt"}))
+	api.ServeHTTP(httptest.NewRecorder(), req)
+
+	if key != "principal:jwt:user-1" {
+		t.Errorf("Expected the principal key, got %q", key)
+	}
+}
+
+func TestRateLimitSkipsEmptyKey(t *testing.T) {
+	mw := RateLimit(Limit{Requests: 1, Period: time.Minute}, WithKeyFunc(func(r *http.Request) string {
+		return ""
+	}))
+
+	api := rest.New(make(chan os.Signal, 1), mw)
+	api.Handle(http.MethodGet, "/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, "ok", http.StatusOK)
+	})
+
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package middleware
+
+import (
+	// Standard library packages
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+// HeaderRequestTimeout is the header a client sets to the number of seconds
+// it is willing to wait for the response, e.g. "2.5".
+const HeaderRequestTimeout = "X-Request-Timeout"
+
+var (
+	// ErrServiceTimeout is the error sent when the handler did not complete
+	// in time.
+	ErrServiceTimeout = errors.New("the request did not complete in time")
+
+	// ErrGatewayTimeout is the error sent when the handler failed because a
+	// dependency did not answer in time.
+	ErrGatewayTimeout = errors.New("a dependency did not answer in time")
+)
+
+// TimeoutOption configures the Timeout middleware.
+type TimeoutOption interface {
+	apply(*timeoutOptions)
+}
+
+// timeoutOptions holds the settings of the Timeout middleware.
+type timeoutOptions struct {
+	// routes holds the timeouts keyed by "METHOD pattern".
+	routes map[string]time.Duration
+	// header is the header of the client timeout, none when empty.
+	header string
+}
+
+type timeoutOptionFunc func(*timeoutOptions)
+
+func (f timeoutOptionFunc) apply(o *timeoutOptions) { f(o) }
+
+// WithRouteTimeout overrides the timeout of a route, identified by its method
+// and the pattern it was registered with, e.g. "POST /sites/{id}/backups". A
+// zero timeout disables the timeout, e.g. for the streaming routes.
+func WithRouteTimeout(route string, d time.Duration) TimeoutOption {
+	return timeoutOptionFunc(func(o *timeoutOptions) {
+		o.routes[route] = d
+	})
+}
+
+// WithClientTimeoutHeader sets the header of the client timeout,
+// HeaderRequestTimeout by default. An empty name ignores the client timeout.
+func WithClientTimeoutHeader(name string) TimeoutOption {
+	return timeoutOptionFunc(func(o *timeoutOptions) {
+		o.header = name
+	})
+}
+
+// Timeout bounds the time spent handling a request with a deadline set on its
+// context. The client can ask for a shorter deadline with the
+// HeaderRequestTimeout header, capped by the timeout of the route.
+//
+// The handler runs in its own goroutine. When the deadline is exceeded, the
+// client receives a 503 with the Response envelope and the writes of the
+// handler, which keeps running until it notices the context is done, are
+// discarded. A handler failing with an error caused by a deadline, e.g. of a
+// slow dependency, is answered with a 504. The error is returned in both
+// cases, and when the handler already started the response, which can not be
+// replaced. It should be registered after the Errors middleware.
+//
+// A panic of the handler is propagated to the middleware recovering from it
+// along with the stack of the handler. A panic after the deadline is reported
+// to the error handler of the API, as the request already completed.
+//
+// Hijacking the connection is not supported under a timeout, disable the
+// timeout of such routes with WithRouteTimeout.
+func Timeout(d time.Duration, opts ...TimeoutOption) rest.Middleware {
+	o := timeoutOptions{
+		routes: make(map[string]time.Duration),
+		header: HeaderRequestTimeout,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler rest.Handler) rest.Handler {
+		// Create the handler that will be attached in the middleware chain.
+		h := rest.Handler(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			v, err := rest.GetContextValues(ctx)
+			if err != nil {
+				return err
+			}
+
+			timeout, ok := o.routes[r.Method+" "+v.Path]
+			if !ok {
+				timeout = d
+			}
+
+			if timeout <= 0 {
+				return handler(ctx, w, r)
+			}
+
+			if o.header != "" {
+				if ct, ok := clientTimeout(r.Header.Get(o.header), timeout); ok {
+					timeout = ct
+				}
+			}
+
+			return runWithTimeout(ctx, w, r, handler, timeout)
+		})
+
+		return h
+	}
+
+	return m
+}
+
+// handlerResult is the outcome of a handler run in its own goroutine.
+type handlerResult struct {
+	err   error
+	panic *panicError
+}
+
+// runWithTimeout runs the handler with a deadline.
+func runWithTimeout(ctx context.Context, w http.ResponseWriter, r *http.Request, handler rest.Handler, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// The handler works on its own copy of the context values, as it can
+	// outlive this middleware.
+	hctx, hv, err := rest.CopyContextValues(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := &timeoutWriter{
+		w: w,
+		h: w.Header().Clone(),
+	}
+
+	done := make(chan handlerResult, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				pe := &panicError{value: p, stack: debug.Stack()}
+
+				// Nobody waits for the result once the request timed out.
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+
+				if tw.timedOut {
+					_ = rest.ReportError(hctx, r, pe)
+					return
+				}
+
+				done <- handlerResult{panic: pe}
+			}
+		}()
+
+		done <- handlerResult{err: handler(hctx, tw, r.WithContext(hctx))}
+	}()
+
+	select {
+	case res := <-done:
+		return finishHandler(ctx, w, tw, hv, res)
+
+	case <-ctx.Done():
+		tw.mu.Lock()
+
+		// The handler may have completed right at the deadline.
+		select {
+		case res := <-done:
+			tw.mu.Unlock()
+			return finishHandler(ctx, w, tw, hv, res)
+		default:
+		}
+
+		defer tw.mu.Unlock()
+		tw.timedOut = true
+
+		err := fmt.Errorf("handler timed out after %s: %w", timeout, ctx.Err())
+
+		// The response can not be replaced once started, and there is nobody
+		// to answer when the client is gone.
+		if tw.wroteHeader || errors.Is(ctx.Err(), context.Canceled) {
+			return err
+		}
+
+		if err := respondTimeout(ctx, w, http.StatusServiceUnavailable, ErrServiceTimeout); err != nil {
+			return err
+		}
+
+		return err
+	}
+}
+
+// finishHandler completes the request once the handler returned in time.
+func finishHandler(ctx context.Context, w http.ResponseWriter, tw *timeoutWriter, hv *rest.ContextValues, res handlerResult) error {
+	// Propagate the panic to the middleware recovering from it.
+	if res.panic != nil {
+		panic(res.panic)
+	}
+
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	mergeContextValues(ctx, hv)
+
+	// A failure caused by a deadline, e.g. of a slow dependency, is reported
+	// as a gateway timeout.
+	if res.err != nil && errors.Is(res.err, context.DeadlineExceeded) && !tw.wroteHeader {
+		if err := respondTimeout(ctx, w, http.StatusGatewayTimeout, ErrGatewayTimeout); err != nil {
+			return err
+		}
+	}
+
+	return res.err
+}
+
+// respondTimeout sends the timeout error to the client.
+func respondTimeout(ctx context.Context, w http.ResponseWriter, statusCode int, err error) error {
+	_ = rest.SetIsError(ctx)
+	er := rest.ErrorResponse{
+		Code:    rest.CodeTimeout,
+		Message: err.Error(),
+	}
+
+	return rest.Respond(ctx, w, er, statusCode)
+}
+
+// mergeContextValues reports the values set by the handler in the context
+// values of the request.
+func mergeContextValues(ctx context.Context, hv *rest.ContextValues) {
+	v, err := rest.GetContextValues(ctx)
+	if err != nil {
+		return
+	}
+
+	if hv.IsError {
+		v.IsError = true
+	}
+	if v.StatusCode == 0 {
+		v.StatusCode = hv.StatusCode
+	}
+	if v.Principal == nil {
+		v.Principal = hv.Principal
+	}
+}
+
+// clientTimeout parses the client timeout, in seconds. It returns false when
+// the value is invalid or not shorter than the server timeout, which applies
+// then.
+func clientTimeout(value string, timeout time.Duration) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	s, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(s) || math.IsInf(s, 0) || s <= 0 {
+		return 0, false
+	}
+
+	// Compared in seconds, as the conversion overflows for large values.
+	if s >= timeout.Seconds() {
+		return 0, false
+	}
+
+	d := time.Duration(s * float64(time.Second))
+	if d <= 0 {
+		return 0, false
+	}
+
+	return d, true
+}
+
+// timeoutWriter is the response writer of a handler run under a timeout. The
+// handler has its own headers, sent along the first write, and its writes
+// are discarded once the request timed out.
+type timeoutWriter struct {
+	w http.ResponseWriter
+	h http.Header
+
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+// Header implements the http.ResponseWriter interface.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+
+	tw.writeHeader(statusCode)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+
+	return tw.w.Write(b)
+}
+
+// FlushError flushes the response, used by http.ResponseController.
+func (tw *timeoutWriter) FlushError() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return http.ErrHandlerTimeout
+	}
+
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+
+	return http.NewResponseController(tw.w).Flush()
+}
+
+// Flush implements the http.Flusher interface.
+func (tw *timeoutWriter) Flush() {
+	_ = tw.FlushError()
+}
+
+// Hijack implements the http.Hijacker interface. Hijacking is not supported
+// under a timeout.
+func (tw *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrNotSupported
+}
+
+// SetWriteDeadline sets the write deadline of the connection, used by
+// http.ResponseController.
+func (tw *timeoutWriter) SetWriteDeadline(deadline time.Time) error {
+	return http.NewResponseController(tw.w).SetWriteDeadline(deadline)
+}
+
+// writeHeader sends the headers of the handler. It must be called with the
+// lock held.
+func (tw *timeoutWriter) writeHeader(statusCode int) {
+	dst := tw.w.Header()
+	for k := range dst {
+		if _, ok := tw.h[k]; !ok {
+			delete(dst, k)
+		}
+	}
+	for k, vv := range tw.h {
+		dst[k] = vv
+	}
+
+	tw.wroteHeader = true
+	tw.w.WriteHeader(statusCode)
+}
@@ -0,0 +1,253 @@
+//go:build unit
+// +build unit
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+	// Pantheon internal package
+	"github.com/coderkakarrot/go-pkg-lib/api/rest"
+)
+
+func TestTimeout(t *testing.T) {
+	// lateWrites receives the result of the writes of the slow handlers.
+	lateWrites := make(chan error, 10)
+
+	api := rest.NewWithOptions(make(chan os.Signal, 1),
+		rest.WithMiddleware(Errors(), Timeout(50*time.Millisecond,
+			WithRouteTimeout("GET /reports", 0),
+			WithRouteTimeout("POST /exports", time.Second),
+		)),
+		// The timeouts are reported to the error handler.
+		rest.WithErrorHandler(func(ctx context.Context, r *http.Request, err error) {}),
+	)
+
+	fast := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return rest.Respond(ctx, w, "ok", http.StatusOK)
+	}
+	// slow ignores the deadline, it returns after 150ms whatever happens.
+	slow := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		time.Sleep(150 * time.Millisecond)
+		w.Header().Set("X-Late", "true")
+		_, err := w.Write([]byte("late"))
+		lateWrites <- err
+		return nil
+	}
+
+	api.Handle(http.MethodGet, "/sites", fast)
+	api.Handle(http.MethodGet, "/slow", slow)
+	api.Handle(http.MethodGet, "/reports", slow)
+	api.Handle(http.MethodPost, "/exports", slow)
+	api.Handle(http.MethodGet, "/downstream", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		dctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+		defer cancel()
+
+		<-dctx.Done()
+		return fmt.Errorf("downstream: %w", dctx.Err())
+	})
+
+	testCases := []struct {
+		name     string
+		method   string
+		target   string
+		header   string
+		status   int
+		timedOut bool
+	}{
+		{name: "In Time", method: http.MethodGet, target: "/sites", status: http.StatusOK},
+		{name: "Timed Out", method: http.MethodGet, target: "/slow", status: http.StatusServiceUnavailable, timedOut: true},
+		{name: "Route Without Timeout", method: http.MethodGet, target: "/reports", status: http.StatusOK},
+		{name: "Route With Longer Timeout", method: http.MethodPost, target: "/exports", status: http.StatusOK},
+		{name: "Shorter Client Timeout", method: http.MethodPost, target: "/exports", header: "0.02", status: http.StatusServiceUnavailable, timedOut: true},
+		{name: "Client Timeout Capped", method: http.MethodGet, target: "/slow", header: "10", status: http.StatusServiceUnavailable, timedOut: true},
+		{name: "Downstream Timeout", method: http.MethodGet, target: "/downstream", status: http.StatusGatewayTimeout},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			if tc.header != "" {
+				req.Header.Set(HeaderRequestTimeout, tc.header)
+			}
+
+			start := time.Now()
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+			elapsed := time.Since(start)
+
+			if rr.Code != tc.status {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tc.status)
+			}
+
+			if !tc.timedOut {
+				// The writes of the handlers completing in time succeed.
+				for len(lateWrites) > 0 {
+					if err := <-lateWrites; err != nil {
+						t.Errorf("Expected the write in time to succeed, got %v", err)
+					}
+				}
+				return
+			}
+
+			if elapsed > 100*time.Millisecond {
+				t.Errorf("Expected the response to be sent at the deadline, took %v", elapsed)
+			}
+
+			// The handler completes later, its writes are discarded.
+			select {
+			case err := <-lateWrites:
+				if !errors.Is(err, http.ErrHandlerTimeout) {
+					t.Errorf("Expected the late write to fail with %v, got %v", http.ErrHandlerTimeout, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Expected the slow handler to complete")
+			}
+
+			if rr.Header().Get("X-Late") != "" || rr.Body.String() == "late" {
+				t.Error("Expected the late response to be discarded")
+			}
+		})
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{value: ""},
+		{value: "2.5", expected: 2500 * time.Millisecond, ok: true},
+		{value: "0.02", expected: 20 * time.Millisecond, ok: true},
+		{value: "10"},
+		{value: "30"},
+		{value: "1e20"},
+		{value: "0"},
+		{value: "-1"},
+		{value: "1e-12"},
+		{value: "NaN"},
+		{value: "Inf"},
+		{value: "-Inf"},
+		{value: "abc"},
+	}
+
+	for _, tc := range testCases {
+		d, ok := clientTimeout(tc.value, 10*time.Second)
+		if d != tc.expected || ok != tc.ok {
+			t.Errorf("clientTimeout(%q): got %v %v want %v %v", tc.value, d, ok, tc.expected, tc.ok)
+		}
+	}
+}
+
+// timeoutPanicHandler panics, its name is looked for in the reported stack.
+func timeoutPanicHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	panic("simulated handler panic")
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	api := rest.New(make(chan os.Signal, 1), Errors(), Panics(nil), Timeout(time.Second))
+	api.Handle(http.MethodGet, "/panic", timeoutPanicHandler)
+
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestTimeoutPanicStack(t *testing.T) {
+	errs := make(chan error, 1)
+	api := rest.NewWithOptions(make(chan os.Signal, 1),
+		rest.WithMiddleware(Panics(nil), Timeout(time.Second)),
+		rest.WithErrorHandler(func(ctx context.Context, r *http.Request, err error) {
+			errs <- err
+		}),
+	)
+	api.Handle(http.MethodGet, "/panic", timeoutPanicHandler)
+
+	api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	err := <-errs
+	if !strings.Contains(err.Error(), "simulated handler panic") {
+		t.Errorf("Expected panic value in error, got %q", err.Error())
+	}
+	// The stack is the one of the handler goroutine.
+	if !strings.Contains(err.Error(), "timeoutPanicHandler") {
+		t.Errorf("Expected the handler stack in error, got %q", err.Error())
+	}
+}
+
+func TestTimeoutLatePanic(t *testing.T) {
+	errs := make(chan error, 2)
+	api := rest.NewWithOptions(make(chan os.Signal, 1),
+		rest.WithMiddleware(Panics(nil), Timeout(20*time.Millisecond)),
+		rest.WithErrorHandler(func(ctx context.Context, r *http.Request, err error) {
+			errs <- err
+		}),
+	)
+	release := make(chan struct{})
+	api.Handle(http.MethodGet, "/panic", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		<-release
+		panic("late handler panic")
+	})
+
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	close(release)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	// The timeout is reported first, the panic once the handler notices it.
+	timer := time.NewTimer(time.Second)
+	defer timer.Stop()
+	for {
+		select {
+		case err := <-errs:
+			if strings.Contains(err.Error(), "late handler panic") {
+				if !strings.Contains(err.Error(), "TRACE") {
+					t.Errorf("Expected stack trace in error, got %q", err.Error())
+				}
+				return
+			}
+		case <-timer.C:
+			t.Fatal("Expected the panic after the deadline to be reported to the error handler")
+		}
+	}
+}
+
+func TestTimeoutContextValues(t *testing.T) {
+	var got rest.ContextValues
+
+	inspect := func(next rest.Handler) rest.Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			err := next(ctx, w, r)
+			v, _ := rest.GetContextValues(ctx)
+			got = *v
+			return err
+		}
+	}
+
+	api := rest.New(make(chan os.Signal, 1), inspect, Timeout(time.Second))
+	api.Handle(http.MethodGet, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_ = rest.SetPrincipal(ctx, &rest.Principal{Subject: "user-1"})
+		_ = rest.SetIsError(ctx)
+		return rest.Respond(ctx, w, "not found", http.StatusNotFound)
+	})
+
+	api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/sites", nil))
+
+	if got.StatusCode != http.StatusNotFound || !got.IsError || got.Principal == nil {
+		t.Errorf("Expected the values of the handler to be reported, got %+v", got)
+	}
+}
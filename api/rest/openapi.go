@@ -0,0 +1,816 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIVersion is the version of the OpenAPI specification of the
+// generated documents.
+const openAPIVersion = "3.1.0"
+
+// OpenAPIInfo describes the API in the OpenAPI document.
+type OpenAPIInfo struct {
+	// Title is the name of the API.
+	Title string
+	// Version is the version of the API, not of the specification.
+	Version string
+	// Description is a description of the API, CommonMark is supported.
+	Description string
+	// Servers are the base URLs of the API, e.g. https://api.example.com.
+	Servers []string
+}
+
+// Operation documents a route in the OpenAPI document. The zero value
+// documents the path parameters and the documents of the encoder only.
+type Operation struct {
+	// OperationID identifies the operation for the generated clients. It is
+	// derived from the method and the path when empty, e.g. getSitesId.
+	OperationID string
+	// Summary is a short summary of what the operation does.
+	Summary string
+	// Description is a verbose explanation of the operation.
+	Description string
+	// Tags groups the operations in the documentation.
+	Tags []string
+	// Deprecated flags an operation which should not be used anymore.
+	Deprecated bool
+	// Hidden leaves the route out of the document.
+	Hidden bool
+	// Params are the query and header parameters. The path parameters are
+	// documented from the route pattern unless they are listed here.
+	Params []Param
+	// Request is a value of the type of the request body, none when nil.
+	Request interface{}
+	// Response is a value of the type of the data of the successful
+	// response, none when nil.
+	Response interface{}
+	// StatusCode is the status code of the successful response, 200 by
+	// default.
+	StatusCode int
+	// Paginated adds the pagination to the envelope of the successful
+	// response.
+	Paginated bool
+	// Errors are the errors the operation responds with.
+	Errors []OperationError
+	// Encoder is the encoder of the route when it is not the one of the API,
+	// e.g. selected with UseEncoder.
+	Encoder Encoder
+}
+
+// Param documents a parameter of an operation.
+type Param struct {
+	// Name is the name of the parameter.
+	Name string
+	// In is the location of the parameter: path, query or header.
+	In string
+	// Description is a description of the parameter.
+	Description string
+	// Required flags a parameter which must be sent.
+	Required bool
+	// Type is a value of the type of the parameter, string when nil.
+	Type interface{}
+}
+
+// OperationError documents an error response of an operation.
+type OperationError struct {
+	// StatusCode is the status code of the response.
+	StatusCode int
+	// Code is the machine-readable code of the ErrorResponse, e.g.
+	// CodeInvalidParameter.
+	Code string
+	// Description tells when the error happens.
+	Description string
+}
+
+// documentedRoute is a route recorded for the OpenAPI document.
+type documentedRoute struct {
+	method string
+	path   string
+	op     Operation
+}
+
+// HandleDoc sets a handler function for a given HTTP method and path pair,
+// like Handle, and documents it in the OpenAPI document with the operation.
+func (a *API) HandleDoc(method string, path string, op Operation, handler Handler, mw ...Middleware) {
+	a.handle(method, path, op, handler, nil, mw)
+}
+
+// HandleOpenAPI serves the OpenAPI 3.1 document of the API at the given path,
+// in YAML when the client prefers application/yaml and in JSON otherwise.
+// The document is generated on each request so it covers all the routes,
+// whenever they were registered.
+//
+//	path: path of the document, e.g. "/openapi"
+//	info: description of the API
+//	mw: list of middleware to execute on each request of the document
+func (a *API) HandleOpenAPI(path string, info OpenAPIInfo, mw ...Middleware) {
+	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		b, err := a.OpenAPI(info)
+		if err != nil {
+			return err
+		}
+
+		ct := "application/json"
+
+		ranges := parseAccept(r.Header.Get("Accept"))
+		if quality(ranges, "application/yaml") > quality(ranges, "application/json") {
+			b, err = jsonToYAML(b)
+			if err != nil {
+				return err
+			}
+			ct = "application/yaml"
+		}
+
+		if err := SetStatusCode(ctx, http.StatusOK); err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", ct)
+		w.Header().Add("Vary", "Accept")
+		w.WriteHeader(http.StatusOK)
+
+		if _, err := w.Write(b); err != nil {
+			return fmt.Errorf("write fail: %w", err)
+		}
+
+		return nil
+	}
+
+	a.HandleDoc(http.MethodGet, path, Operation{Hidden: true}, h, mw...)
+}
+
+// OpenAPI returns the OpenAPI 3.1 document of the routes registered so far,
+// in JSON. The request and response bodies are described by reflecting their
+// Go types, in the documents built by the encoder of the route, and are
+// available in every format of the API. The documents of the EnvelopeEncoder
+// and of the ProblemEncoder are described, the ones of the other encoders
+// are described as any value. The routes registered without a method, which
+// match all of them, are left out.
+func (a *API) OpenAPI(info OpenAPIInfo) ([]byte, error) {
+	doc := openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: openAPIInfo{
+			Title:       info.Title,
+			Version:     info.Version,
+			Description: info.Description,
+		},
+		Paths: make(map[string]map[string]*openAPIOperation),
+	}
+
+	for _, url := range info.Servers {
+		doc.Servers = append(doc.Servers, openAPIServer{URL: url})
+	}
+
+	g := newSchemaGenerator()
+
+	// The operation IDs must be unique. The IDs set by the application are
+	// kept and the generated ones get a numeric suffix when already used.
+	used := make(map[string]bool)
+	for _, dr := range a.documented {
+		if documented(dr) && dr.op.OperationID != "" {
+			used[dr.op.OperationID] = true
+		}
+	}
+
+	for _, dr := range a.documented {
+		if !documented(dr) {
+			continue
+		}
+
+		path, wildcards := openAPIPath(dr.path)
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(map[string]*openAPIOperation)
+			doc.Paths[path] = item
+		}
+
+		enc := dr.op.Encoder
+		if enc == nil {
+			enc = a.encoder
+		}
+
+		o := g.operation(dr.method, path, wildcards, dr.op, enc, a.codecs.codecs)
+		if dr.op.OperationID == "" {
+			o.OperationID = uniqueOperationID(used, o.OperationID)
+		}
+
+		item[strings.ToLower(dr.method)] = o
+	}
+
+	doc.Components.Schemas = g.schemas
+
+	return json.Marshal(doc)
+}
+
+// documented tells whether the route is part of the OpenAPI document.
+func documented(dr documentedRoute) bool {
+	return !dr.op.Hidden && dr.method != ""
+}
+
+// openAPIPath converts a route pattern to an OpenAPI path and returns the
+// names of its wildcards, e.g. "/files/{path...}" becomes "/files/{path}".
+func openAPIPath(pattern string) (string, []string) {
+	// The host of the pattern is not part of the path.
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+
+	var wildcards []string
+
+	segments := strings.Split(pattern, "/")
+	for i, s := range segments {
+		if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+			continue
+		}
+
+		name := strings.TrimSuffix(s[1:len(s)-1], "...")
+		if name == "$" {
+			segments[i] = ""
+			continue
+		}
+
+		wildcards = append(wildcards, name)
+		segments[i] = "{" + name + "}"
+	}
+
+	return strings.Join(segments, "/"), wildcards
+}
+
+// operationID derives the ID of an operation from its method and path, e.g.
+// getSitesId for GET /sites/{id}.
+func operationID(method string, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+
+	words := strings.FieldsFunc(path, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+
+	return b.String()
+}
+
+// uniqueOperationID returns the operation ID, with a numeric suffix when it is
+// already used, and records it as used.
+func uniqueOperationID(used map[string]bool, id string) string {
+	unique := id
+	for n := 2; used[unique]; n++ {
+		unique = id + strconv.Itoa(n)
+	}
+
+	used[unique] = true
+
+	return unique
+}
+
+// jsonToYAML converts a JSON document to YAML, keeping the order of the
+// members.
+func jsonToYAML(b []byte) ([]byte, error) {
+	// JSON is YAML written in the flow style.
+	var n yaml.Node
+	if err := yaml.Unmarshal(b, &n); err != nil {
+		return nil, err
+	}
+
+	blockStyle(&n)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&n); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// blockStyle resets the style of the node and its children, so they are
+// written in the block style and the strings are quoted only when needed.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+// openAPIDocument is the root of an OpenAPI document.
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Servers    []openAPIServer                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+// openAPIInfo is the info object of an OpenAPI document.
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// openAPIServer is a server object of an OpenAPI document.
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+// openAPIOperation is an operation object of an OpenAPI document.
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+// openAPIParameter is a parameter object of an OpenAPI document.
+type openAPIParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Schema      *jsonSchema `json:"schema"`
+}
+
+// openAPIRequestBody is a request body object of an OpenAPI document.
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+// openAPIResponse is a response object of an OpenAPI document.
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+// openAPIMediaType is a media type object of an OpenAPI document.
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+// openAPIComponents holds the reusable schemas of an OpenAPI document.
+type openAPIComponents struct {
+	Schemas map[string]*jsonSchema `json:"schemas,omitempty"`
+}
+
+// jsonSchema is the subset of JSON Schema used to describe the Go types.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaGenerator builds the schemas of the Go types. The named struct types
+// and the documents of the encoders are described once in the components of
+// the document.
+type schemaGenerator struct {
+	schemas map[string]*jsonSchema
+	names   map[reflect.Type]string
+	// documents holds the names of the documents of the encoders.
+	documents map[string]string
+}
+
+// newSchemaGenerator creates a schema generator.
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		schemas:   make(map[string]*jsonSchema),
+		names:     make(map[reflect.Type]string),
+		documents: make(map[string]string),
+	}
+}
+
+// operation builds the operation object of a route, with the documents
+// built by the encoder in every format of the codecs.
+func (g *schemaGenerator) operation(method string, path string, wildcards []string, op Operation, enc Encoder, codecs []Codec) *openAPIOperation {
+	o := &openAPIOperation{
+		OperationID: op.OperationID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Deprecated:  op.Deprecated,
+		Responses:   make(map[string]*openAPIResponse),
+	}
+
+	if o.OperationID == "" {
+		o.OperationID = operationID(method, path)
+	}
+
+	// Parameters.
+	listed := make(map[string]bool, len(op.Params))
+	for _, p := range op.Params {
+		listed[p.In+" "+p.Name] = true
+	}
+
+	for _, name := range wildcards {
+		if !listed["path "+name] {
+			o.Parameters = append(o.Parameters, openAPIParameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &jsonSchema{Type: "string"},
+			})
+		}
+	}
+
+	for _, p := range op.Params {
+		schema := &jsonSchema{Type: "string"}
+		if p.Type != nil {
+			schema = g.schema(reflect.TypeOf(p.Type))
+		}
+
+		o.Parameters = append(o.Parameters, openAPIParameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required || p.In == "path",
+			Schema:      schema,
+		})
+	}
+
+	content := func(schema *jsonSchema, encoderType string) map[string]openAPIMediaType {
+		c := make(map[string]openAPIMediaType, len(codecs))
+		for _, codec := range codecs {
+			c[contentType(encoderType, codec)] = openAPIMediaType{Schema: schema}
+		}
+		return c
+	}
+
+	// Request body.
+	if op.Request != nil {
+		o.RequestBody = &openAPIRequestBody{
+			Required: true,
+			Content:  content(g.schema(reflect.TypeOf(op.Request)), "application/json"),
+		}
+	}
+
+	// Successful response.
+	statusCode := op.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	success := &openAPIResponse{Description: http.StatusText(statusCode)}
+	if statusCode != http.StatusNoContent {
+		var data *jsonSchema
+		if op.Response != nil {
+			data = g.schema(reflect.TypeOf(op.Response))
+		}
+		if doc := g.successDocument(enc, data, op.Paginated); doc != nil {
+			success.Content = content(doc, "application/json")
+		}
+	}
+	o.Responses[strconv.Itoa(statusCode)] = success
+
+	// Error responses.
+	errs := op.Errors
+	if op.Request != nil && !hasErrorStatus(errs, http.StatusBadRequest) {
+		errs = append(errs, OperationError{
+			StatusCode:  http.StatusBadRequest,
+			Code:        CodeInvalidBody,
+			Description: "The request body is malformed or invalid.",
+		})
+	}
+
+	errorContent := content(g.errorDocument(enc))
+	for _, e := range errs {
+		key := strconv.Itoa(e.StatusCode)
+
+		desc := e.Description
+		if desc == "" {
+			desc = http.StatusText(e.StatusCode)
+		}
+		if e.Code != "" {
+			desc += " (" + e.Code + ")"
+		}
+
+		if r, ok := o.Responses[key]; ok {
+			r.Description += "\n" + desc
+			continue
+		}
+
+		o.Responses[key] = &openAPIResponse{Description: desc, Content: errorContent}
+	}
+
+	o.Responses["default"] = &openAPIResponse{Description: "Unexpected error.", Content: errorContent}
+
+	return o
+}
+
+// hasErrorStatus tells whether one of the errors has the status code.
+func hasErrorStatus(errs []OperationError, statusCode int) bool {
+	for _, e := range errs {
+		if e.StatusCode == statusCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// successDocument returns the schema of the document built by the encoder
+// for a successful response holding the data, none when there is no
+// document.
+func (g *schemaGenerator) successDocument(enc Encoder, data *jsonSchema, paginated bool) *jsonSchema {
+	switch enc.(type) {
+	case EnvelopeEncoder, *EnvelopeEncoder:
+		return g.envelope(data, paginated)
+	case ProblemEncoder, *ProblemEncoder:
+		return data
+	default:
+		return &jsonSchema{}
+	}
+}
+
+// errorDocument returns the schema of the document built by the encoder for
+// a failed response, along with its content type for a JSON representation.
+func (g *schemaGenerator) errorDocument(enc Encoder) (*jsonSchema, string) {
+	switch enc.(type) {
+	case EnvelopeEncoder, *EnvelopeEncoder:
+		return g.errorEnvelope(), "application/json"
+	case ProblemEncoder, *ProblemEncoder:
+		return g.problem(), "application/problem+json"
+	default:
+		return &jsonSchema{}, "application/json"
+	}
+}
+
+// envelope returns the schema of the Response envelope of a successful
+// response holding the data, none when nil.
+func (g *schemaGenerator) envelope(data *jsonSchema, paginated bool) *jsonSchema {
+	s := &jsonSchema{
+		Type: "object",
+		Properties: map[string]*jsonSchema{
+			"success":    {Type: "boolean"},
+			"timestamp":  {Type: "integer", Format: "int64"},
+			"request_id": {Type: "string"},
+		},
+		Required: []string{"success", "timestamp"},
+	}
+
+	if data != nil {
+		s.Properties["data"] = data
+		s.Required = append(s.Required, "data")
+	}
+
+	if paginated {
+		s.Properties["pagination"] = g.schema(reflect.TypeOf(Pagination{}))
+		s.Required = append(s.Required, "pagination")
+	}
+
+	return s
+}
+
+// errorEnvelope returns the schema of the Response envelope of a failed
+// response, described once in the components.
+func (g *schemaGenerator) errorEnvelope() *jsonSchema {
+	return g.document("Error", func() *jsonSchema {
+		return &jsonSchema{
+			Type: "object",
+			Properties: map[string]*jsonSchema{
+				"success":    {Type: "boolean"},
+				"timestamp":  {Type: "integer", Format: "int64"},
+				"request_id": {Type: "string"},
+				"errors":     g.schema(reflect.TypeOf(ErrorResponse{})),
+			},
+			Required: []string{"success", "timestamp", "errors"},
+		}
+	})
+}
+
+// problem returns the schema of the Problem document of a failed response
+// sent by the ProblemEncoder, described once in the components.
+func (g *schemaGenerator) problem() *jsonSchema {
+	return g.document("Problem", func() *jsonSchema {
+		return &jsonSchema{
+			Type: "object",
+			Properties: map[string]*jsonSchema{
+				"type":       {Type: "string"},
+				"title":      {Type: "string"},
+				"status":     {Type: "integer", Format: "int64"},
+				"detail":     {Type: "string"},
+				"instance":   {Type: "string"},
+				"request_id": {Type: "string"},
+				"code":       {Type: "string"},
+				"fields":     g.schema(reflect.TypeOf([]FieldError{})),
+			},
+			Required: []string{"status", "title", "type"},
+		}
+	})
+}
+
+// document returns a reference to the schema of a document of the encoders,
+// adding it to the components under a unique name the first time.
+func (g *schemaGenerator) document(name string, schema func() *jsonSchema) *jsonSchema {
+	unique, ok := g.documents[name]
+	if !ok {
+		unique = g.uniqueName(name, reflect.TypeOf(Response{}).PkgPath())
+		g.documents[name] = unique
+		g.schemas[unique] = schema()
+	}
+
+	return &jsonSchema{Ref: "#/components/schemas/" + unique}
+}
+
+// schema returns the schema of the type, following the encoding/json rules.
+// A pointer is described by the schema of its element.
+func (g *schemaGenerator) schema(t reflect.Type) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	case t == rawMessageType, t.Implements(jsonMarshalerType):
+		return &jsonSchema{}
+	case t.Implements(textMarshalerType):
+		return &jsonSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &jsonSchema{Type: "integer", Format: "int64"}
+
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &jsonSchema{Type: "integer", Format: "int32"}
+
+	case reflect.Float32:
+		return &jsonSchema{Type: "number", Format: "float"}
+
+	case reflect.Float64:
+		return &jsonSchema{Type: "number", Format: "double"}
+
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		// Byte slices are encoded in base64.
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &jsonSchema{Type: "string", Format: "byte"}
+		}
+
+		return &jsonSchema{Type: "array", Items: g.schema(t.Elem())}
+
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		return g.ref(t)
+
+	default:
+		// Any value, e.g. an interface.
+		return &jsonSchema{}
+	}
+}
+
+// ref returns a reference to the schema of the named struct type, adding
+// it to the components the first time.
+func (g *schemaGenerator) ref(t reflect.Type) *jsonSchema {
+	name, ok := g.names[t]
+	if !ok {
+		name = g.schemaName(t)
+		g.names[t] = name
+
+		// Reserve the name first as the type can be recursive.
+		g.schemas[name] = &jsonSchema{}
+		g.schemas[name] = g.structSchema(t)
+	}
+
+	return &jsonSchema{Ref: "#/components/schemas/" + name}
+}
+
+// schemaName returns a unique name in the components for the type, prefixed
+// with its package name when another type has the same name.
+func (g *schemaGenerator) schemaName(t reflect.Type) string {
+	return g.uniqueName(t.Name(), t.PkgPath())
+}
+
+// uniqueName returns a unique name in the components, prefixed with the name
+// of the package when already used.
+func (g *schemaGenerator) uniqueName(name string, pkg string) string {
+	clean := func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, s)
+	}
+
+	name = clean(name)
+	if _, ok := g.schemas[name]; !ok {
+		return name
+	}
+
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	if pkg = clean(pkg); pkg != "" {
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	unique := name
+	for i := 2; ; i++ {
+		if _, ok := g.schemas[unique]; !ok {
+			return unique
+		}
+		unique = name + strconv.Itoa(i)
+	}
+}
+
+// structSchema returns the schema of the struct type. The fields without
+// omitempty are required, the embedded structs are flattened.
+func (g *schemaGenerator) structSchema(t reflect.Type) *jsonSchema {
+	s := &jsonSchema{
+		Type:       "object",
+		Properties: make(map[string]*jsonSchema),
+	}
+
+	g.addFields(s, t)
+	sort.Strings(s.Required)
+
+	return s
+}
+
+// addFields adds the fields of the struct type to the schema.
+func (g *schemaGenerator) addFields(s *jsonSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.addFields(s, ft)
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		s.Properties[name] = g.schema(f.Type)
+
+		if !strings.Contains(","+opts+",", ",omitempty,") && f.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
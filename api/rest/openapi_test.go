@@ -0,0 +1,280 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testDocAudit struct {
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testDocSite struct {
+	testDocAudit
+	ID       int64             `json:"id"`
+	Name     string            `json:"name"`
+	Tags     []string          `json:"tags,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Parent   *testDocSite      `json:"parent,omitempty"`
+	Secret   string            `json:"-"`
+	internal string
+}
+
+type testDocCreateSite struct {
+	Name string `json:"name"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error { return nil }
+
+	api := NewWithOptions(make(chan os.Signal, 1), WithCodecs(JSONCodec{}, XMLCodec{}))
+	v1 := api.Group("/v1")
+	v1.HandleDoc(http.MethodGet, "/sites", Operation{
+		Summary:   "List the sites",
+		Tags:      []string{"sites"},
+		Params:    []Param{{Name: "limit", In: "query", Type: 0}},
+		Response:  []testDocSite{},
+		Paginated: true,
+	}, noop)
+	v1.HandleDoc(http.MethodPost, "/sites", Operation{
+		OperationID: "createSite",
+		Request:     testDocCreateSite{},
+		Response:    testDocSite{},
+		StatusCode:  http.StatusCreated,
+		Errors:      []OperationError{{StatusCode: http.StatusConflict, Code: "site_exists"}},
+	}, noop)
+	v1.Handle(http.MethodDelete, "/sites/{id}", noop)
+	v1.Handle(http.MethodGet, "/sites/{id}", noop)
+	v1.Handle(http.MethodGet, "/sites/id", noop)
+	v1.HandleDoc(http.MethodGet, "/sites/export", Operation{OperationID: "getV1SitesId2"}, noop)
+	v1.Handle("", "/proxy/", noop)
+	api.HandleDoc(http.MethodGet, "/health", Operation{Hidden: true}, noop)
+	api.HandleOpenAPI("/openapi", OpenAPIInfo{Title: "Sites", Version: "1.0.0"})
+
+	b, err := api.OpenAPI(OpenAPIInfo{Title: "Sites", Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("OpenAPI failed: %v", err)
+	}
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("could not unmarshal the document: %v", err)
+	}
+
+	if doc.OpenAPI != "3.1.0" || doc.Info.Title != "Sites" {
+		t.Errorf("unexpected document header: %s", b)
+	}
+	if _, ok := doc.Paths["/health"]; ok {
+		t.Error("Expected the hidden route to be left out")
+	}
+	if _, ok := doc.Paths["/openapi"]; ok {
+		t.Error("Expected the document route to be left out")
+	}
+
+	list := doc.Paths["/v1/sites"]["get"]
+	if list == nil || list.Summary != "List the sites" || list.OperationID != "getV1Sites" {
+		t.Fatalf("unexpected list operation: %+v", list)
+	}
+	if len(list.Parameters) != 1 || list.Parameters[0].Schema.Type != "integer" {
+		t.Errorf("unexpected list parameters: %+v", list.Parameters)
+	}
+
+	envelope := list.Responses["200"].Content["application/json"].Schema
+	if envelope.Properties["data"].Items.Ref != "#/components/schemas/testDocSite" || envelope.Properties["pagination"] == nil {
+		t.Errorf("unexpected list envelope: %+v", envelope)
+	}
+	if _, ok := list.Responses["200"].Content["application/xml"]; !ok {
+		t.Error("Expected the response to be documented for every codec")
+	}
+
+	create := doc.Paths["/v1/sites"]["post"]
+	if create == nil || create.OperationID != "createSite" || create.RequestBody == nil {
+		t.Fatalf("unexpected create operation: %+v", create)
+	}
+	if create.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/testDocCreateSite" {
+		t.Errorf("unexpected request body: %+v", create.RequestBody)
+	}
+	for _, status := range []string{"201", "400", "409", "default"} {
+		if _, ok := create.Responses[status]; !ok {
+			t.Errorf("Expected a %s response", status)
+		}
+	}
+	if !strings.Contains(create.Responses["409"].Description, "site_exists") {
+		t.Errorf("Expected the error code in the description, got %q", create.Responses["409"].Description)
+	}
+
+	del := doc.Paths["/v1/sites/{id}"]["delete"]
+	if del == nil || len(del.Parameters) != 1 || del.Parameters[0].In != "path" || !del.Parameters[0].Required {
+		t.Fatalf("unexpected delete operation: %+v", del)
+	}
+
+	if _, ok := doc.Paths["/v1/proxy/"]; ok {
+		t.Error("Expected the route without method to be left out")
+	}
+
+	// The colliding generated IDs get a suffix, skipping the ones in use.
+	ids := []string{
+		doc.Paths["/v1/sites/{id}"]["get"].OperationID,
+		doc.Paths["/v1/sites/id"]["get"].OperationID,
+		doc.Paths["/v1/sites/export"]["get"].OperationID,
+	}
+	if strings.Join(ids, ",") != "getV1SitesId,getV1SitesId3,getV1SitesId2" {
+		t.Errorf("unexpected operation IDs: %v", ids)
+	}
+
+	site := doc.Components.Schemas["testDocSite"]
+	if site == nil {
+		t.Fatal("Expected the site schema in the components")
+	}
+	for _, name := range []string{"created_at", "id", "name", "tags", "labels", "parent"} {
+		if _, ok := site.Properties[name]; !ok {
+			t.Errorf("Expected the %s property", name)
+		}
+	}
+	if _, ok := site.Properties["Secret"]; ok || len(site.Properties) != 6 {
+		t.Errorf("unexpected properties: %v", site.Properties)
+	}
+	if strings.Join(site.Required, ",") != "created_at,id,name" {
+		t.Errorf("unexpected required properties: %v", site.Required)
+	}
+	if site.Properties["created_at"].Format != "date-time" || site.Properties["parent"].Ref != "#/components/schemas/testDocSite" {
+		t.Errorf("unexpected property schemas: %+v", site.Properties)
+	}
+	if _, ok := doc.Components.Schemas["Error"]; !ok {
+		t.Error("Expected the error envelope in the components")
+	}
+}
+
+func TestOpenAPIEncoder(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error { return nil }
+
+	// A type of the application named like the documents of the encoders.
+	type Error struct {
+		Reason string `json:"reason"`
+	}
+
+	api := NewWithOptions(make(chan os.Signal, 1), WithEncoder(ProblemEncoder{}), WithCodecs(JSONCodec{}, XMLCodec{}))
+	api.HandleDoc(http.MethodGet, "/sites/{id}", Operation{
+		Response: testDocSite{},
+		Errors:   []OperationError{{StatusCode: http.StatusNotFound, Code: "site_not_found"}},
+	}, noop)
+	api.HandleDoc(http.MethodGet, "/errors/{id}", Operation{Response: Error{}}, noop)
+	api.HandleDoc(http.MethodGet, "/legacy/sites/{id}", Operation{Response: testDocSite{}, Encoder: EnvelopeEncoder{}}, noop)
+
+	b, err := api.OpenAPI(OpenAPIInfo{Title: "Sites", Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("OpenAPI failed: %v", err)
+	}
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatalf("could not unmarshal the document: %v", err)
+	}
+
+	// The data is sent without envelope and the errors as problems.
+	get := doc.Paths["/sites/{id}"]["get"]
+	if ref := get.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/testDocSite" {
+		t.Errorf("Expected the data without envelope, got %q", ref)
+	}
+	for _, status := range []string{"404", "default"} {
+		content := get.Responses[status].Content
+		if len(content) != 2 || content["application/problem+json"].Schema == nil || content["application/problem+xml"].Schema == nil {
+			t.Fatalf("Expected the %s response to be a problem, got %+v", status, content)
+		}
+		if ref := content["application/problem+json"].Schema.Ref; ref != "#/components/schemas/Problem" {
+			t.Errorf("Expected the %s response to be a problem, got %q", status, ref)
+		}
+	}
+
+	// The type of the application keeps its name, the error envelope of the
+	// route using the EnvelopeEncoder gets another one.
+	if ref := doc.Paths["/errors/{id}"]["get"].Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/Error" {
+		t.Errorf("Expected the application type to be named Error, got %q", ref)
+	}
+	if _, ok := doc.Components.Schemas["Error"].Properties["reason"]; !ok {
+		t.Errorf("Expected the application type to be kept, got %+v", doc.Components.Schemas["Error"])
+	}
+
+	legacy := doc.Paths["/legacy/sites/{id}"]["get"]
+	if data := legacy.Responses["200"].Content["application/json"].Schema.Properties["data"]; data == nil || data.Ref != "#/components/schemas/testDocSite" {
+		t.Errorf("Expected the data in the envelope, got %+v", legacy.Responses["200"].Content)
+	}
+	ref := legacy.Responses["default"].Content["application/json"].Schema.Ref
+	if ref == "#/components/schemas/Error" || doc.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")] == nil {
+		t.Errorf("Expected the error envelope under its own name, got %q", ref)
+	}
+	if _, ok := doc.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")].Properties["errors"]; !ok {
+		t.Errorf("Expected the error envelope, got %q", ref)
+	}
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	api := New(make(chan os.Signal, 1))
+	api.HandleDoc(http.MethodGet, "/sites/{id}", Operation{Response: testDocSite{}}, func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return nil
+	})
+	api.HandleOpenAPI("/openapi", OpenAPIInfo{Title: "Sites", Version: "1.0.0"})
+
+	testCases := []struct {
+		accept      string
+		contentType string
+		prefix      string
+	}{
+		{accept: "", contentType: "application/json", prefix: `{"openapi":"3.1.0"`},
+		{accept: "application/yaml", contentType: "application/yaml", prefix: "openapi: 3.1.0\n"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/openapi", nil)
+		req.Header.Set("Accept", tc.accept)
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != tc.contentType {
+			t.Fatalf("unexpected response: %d %v", rr.Code, rr.Header())
+		}
+		if !strings.HasPrefix(rr.Body.String(), tc.prefix) {
+			t.Errorf("unexpected document: %s", rr.Body.String())
+		}
+	}
+
+	// The status codes are keys, they must stay strings in YAML.
+	req := httptest.NewRequest(http.MethodGet, "/openapi", nil)
+	req.Header.Set("Accept", "application/yaml")
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, req)
+
+	if !strings.Contains(rr.Body.String(), `"200":`) {
+		t.Errorf("Expected the status codes to be quoted, got %s", rr.Body.String())
+	}
+}
+
+func TestOpenAPIPath(t *testing.T) {
+	testCases := []struct {
+		pattern   string
+		path      string
+		wildcards []string
+	}{
+		{pattern: "/sites", path: "/sites"},
+		{pattern: "/sites/{id}/backups/{backup}", path: "/sites/{id}/backups/{backup}", wildcards: []string{"id", "backup"}},
+		{pattern: "/files/{path...}", path: "/files/{path}", wildcards: []string{"path"}},
+		{pattern: "/sites/{$}", path: "/sites/"},
+		{pattern: "example.com/sites", path: "/sites"},
+	}
+
+	for _, tc := range testCases {
+		path, wildcards := openAPIPath(tc.pattern)
+		if path != tc.path || strings.Join(wildcards, ",") != strings.Join(tc.wildcards, ",") {
+			t.Errorf("openAPIPath(%q): got %q %v want %q %v", tc.pattern, path, wildcards, tc.path, tc.wildcards)
+		}
+	}
+}
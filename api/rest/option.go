@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+)
+
+// ErrorHandler is called by the API for every error which reaches the end of
+// the handler chain and does not require a shutdown.
+type ErrorHandler func(ctx context.Context, r *http.Request, err error)
+
+// Option configures the API created by NewWithOptions.
+type Option interface {
+	apply(*API)
+}
+
+type optionFunc func(*API)
+
+func (f optionFunc) apply(a *API) { f(a) }
+
+// WithMiddleware appends the given middleware to the list of middleware
+// executed on each request.
+func WithMiddleware(mw ...Middleware) Option {
+	return optionFunc(func(a *API) {
+		a.mw = append(a.mw, mw...)
+	})
+}
+
+// WithErrorHandler sets the policy for errors left over by the handler chain.
+// The default policy logs the error using slog.Default and drops it.
+func WithErrorHandler(h ErrorHandler) Option {
+	return optionFunc(func(a *API) {
+		if h != nil {
+			a.errorHandler = h
+		}
+	})
+}
+
+// WithEncoder sets the encoder building the documents sent by Respond. The
+// default encoder is the EnvelopeEncoder. Use the UseEncoder middleware to
+// select an encoder for a route group.
+func WithEncoder(enc Encoder) Option {
+	return optionFunc(func(a *API) {
+		if enc != nil {
+			a.encoder = enc
+		}
+	})
+}
+
+// WithCodecs sets the formats supported for the requests and responses. The
+// first codec is the default one. The default formats are JSON only.
+func WithCodecs(codecs ...Codec) Option {
+	return optionFunc(func(a *API) {
+		if len(codecs) > 0 {
+			a.codecs = NewCodecs(codecs...)
+		}
+	})
+}
+
+// WithETags sets the kind of ETags computed by Respond for the successful
+// responses, none by default. Use the UseETags middleware to select the kind
+// of ETags for a route group.
+func WithETags(mode ETagMode) Option {
+	return optionFunc(func(a *API) {
+		a.etags = mode
+	})
+}
+
+// defaultErrorHandler logs the error and drops it.
+func defaultErrorHandler(ctx context.Context, r *http.Request, err error) {
+	slog.ErrorContext(ctx, "unhandled request error",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("error", err.Error()),
+	)
+}
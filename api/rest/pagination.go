@@ -0,0 +1,313 @@
+package rest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Pagination query parameters.
+const (
+	QueryLimit  = "limit"
+	QueryCursor = "cursor"
+	QueryPage   = "page"
+)
+
+// Default page sizes of a Pager.
+const (
+	DefaultPageLimit = 20
+	DefaultMaxLimit  = 100
+)
+
+// errInvalidCursor is returned when a cursor was not issued by the Pager.
+var errInvalidCursor = errors.New("invalid cursor")
+
+// Pager parses the pagination query parameters of list endpoints and signs
+// the cursors it issues, so clients can not forge them.
+type Pager struct {
+	key          []byte
+	defaultLimit int
+	maxLimit     int
+}
+
+// PagerOption configures the Pager created by NewPager.
+type PagerOption interface {
+	apply(*Pager)
+}
+
+type pagerOptionFunc func(*Pager)
+
+func (f pagerOptionFunc) apply(p *Pager) { f(p) }
+
+// WithPageLimits sets the page size used when the limit parameter is absent
+// and the largest page size a client can ask for.
+func WithPageLimits(defaultLimit, maxLimit int) PagerOption {
+	return pagerOptionFunc(func(p *Pager) {
+		if maxLimit > 0 {
+			p.maxLimit = maxLimit
+		}
+		if defaultLimit > 0 {
+			p.defaultLimit = defaultLimit
+		}
+	})
+}
+
+// NewPager creates a Pager signing the cursors with the given key. A random
+// key is generated when none is given, in which case the cursors are not
+// valid anymore once the process restarts or across replicas.
+func NewPager(key []byte, opts ...PagerOption) *Pager {
+	p := &Pager{
+		key:          key,
+		defaultLimit: DefaultPageLimit,
+		maxLimit:     DefaultMaxLimit,
+	}
+
+	for _, opt := range opts {
+		opt.apply(p)
+	}
+
+	if p.defaultLimit > p.maxLimit {
+		p.defaultLimit = p.maxLimit
+	}
+
+	if len(p.key) == 0 {
+		p.key = make([]byte, sha256.Size)
+		_, _ = rand.Read(p.key)
+	}
+
+	return p
+}
+
+// Page is the page of a collection asked by the client.
+type Page struct {
+	// Limit is the number of items of the page.
+	Limit int
+	// Number is the 1-based page number of an offset pagination, 0 when the
+	// client paginates with a cursor.
+	Number int
+	// Offset is the number of items to skip for an offset pagination.
+	Offset int
+
+	// cursor is the verified payload of the cursor.
+	cursor []byte
+}
+
+// HasCursor reports whether the client asked for the page with a cursor.
+func (pg Page) HasCursor() bool {
+	return pg.cursor != nil
+}
+
+// Cursor decodes the payload of the cursor into v. It does nothing when the
+// client did not send a cursor.
+func (pg Page) Cursor(v interface{}) error {
+	if pg.cursor == nil {
+		return nil
+	}
+
+	return json.Unmarshal(pg.cursor, v)
+}
+
+// Parse returns the page asked by the request from the limit, cursor and
+// page query parameters. Without cursor nor page, the first page is
+// returned. A malformed, out of bounds or forged value returns a
+// RequestError with status 400.
+func (p *Pager) Parse(r *http.Request) (Page, error) {
+	q := r.URL.Query()
+
+	pg := Page{
+		Limit: p.defaultLimit,
+	}
+
+	if v := q.Get(QueryLimit); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > p.maxLimit {
+			return Page{}, invalidQueryParamError(QueryLimit, fmt.Sprintf("an integer between 1 and %d", p.maxLimit))
+		}
+		pg.Limit = limit
+	}
+
+	cursor, page := q.Get(QueryCursor), q.Get(QueryPage)
+	switch {
+	case cursor != "" && page != "":
+		return Page{}, NewRequestError(
+			fmt.Errorf("query parameters %q and %q are mutually exclusive", QueryCursor, QueryPage),
+			http.StatusBadRequest,
+			CodeInvalidParameter,
+		)
+
+	case cursor != "":
+		payload, err := p.verify(cursor)
+		if err != nil {
+			return Page{}, invalidQueryParamError(QueryCursor, "a cursor issued by the API")
+		}
+		pg.cursor = payload
+
+	default:
+		pg.Number = 1
+		if page != "" {
+			// The offset of the page must fit in an int.
+			maxPage := math.MaxInt/pg.Limit + 1
+
+			n, err := strconv.Atoi(page)
+			if err != nil || n < 1 || n > maxPage {
+				return Page{}, invalidQueryParamError(QueryPage, fmt.Sprintf("an integer between 1 and %d", maxPage))
+			}
+			pg.Number = n
+		}
+		pg.Offset = (pg.Number - 1) * pg.Limit
+	}
+
+	return pg, nil
+}
+
+// EncodeCursor returns the opaque cursor holding v, e.g. the sort key of the
+// last item of the page. The payload is JSON encoded and signed, it is not
+// encrypted: clients can read it but can not alter it.
+func (p *Pager) EncodeCursor(v interface{}) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("cursor marshal fail: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(payload, p.signature(payload)...)), nil
+}
+
+// signature returns the HMAC-SHA256 signature of the payload.
+func (p *Pager) signature(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
+
+// verify returns the payload of a cursor after checking its signature.
+func (p *Pager) verify(cursor string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || len(b) <= sha256.Size {
+		return nil, errInvalidCursor
+	}
+
+	payload, sig := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+	if !hmac.Equal(p.signature(payload), sig) {
+		return nil, errInvalidCursor
+	}
+
+	return payload, nil
+}
+
+// Pagination is the pagination metadata of a paginated response.
+type Pagination struct {
+	// Limit is the number of items of the page.
+	Limit int `json:"limit" xml:"limit"`
+	// Page is the 1-based page number of an offset pagination.
+	Page int `json:"page,omitempty" xml:"page,omitempty"`
+	// NextCursor is the cursor of the next page, if any.
+	NextCursor string `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+	// PrevCursor is the cursor of the previous page, if any.
+	PrevCursor string `json:"prev_cursor,omitempty" xml:"prev_cursor,omitempty"`
+	// Total is the number of items of the collection, when known.
+	Total *int64 `json:"total,omitempty" xml:"total,omitempty"`
+	// HasMore reports whether there are items after this page.
+	HasMore bool `json:"has_more" xml:"has_more"`
+}
+
+// lastPage returns the number of the last page when the total is known.
+func (pg Pagination) lastPage() (int, bool) {
+	if pg.Total == nil || pg.Limit < 1 {
+		return 0, false
+	}
+
+	last := int((*pg.Total + int64(pg.Limit) - 1) / int64(pg.Limit))
+	if last < 1 {
+		last = 1
+	}
+
+	return last, true
+}
+
+// RespondPage sends a page of a collection like Respond does, with the
+// pagination metadata added to the Response envelope and the RFC 8288 Link
+// header pointing to the first, previous, next and last pages when known.
+// HasMore is set when a next page is known to exist.
+func RespondPage(ctx context.Context, w http.ResponseWriter, data interface{}, pg Pagination, statusCode int) error {
+	v, err := GetContextValues(ctx)
+	if err != nil {
+		return ErrMissingContext
+	}
+
+	last, hasLast := pg.lastPage()
+	if pg.NextCursor != "" || (pg.Page > 0 && hasLast && pg.Page < last) {
+		pg.HasMore = true
+	}
+
+	// The links are not sent along the error of an unacceptable request.
+	if _, _, ok := negotiateCodec(v); ok && v.request != nil {
+		if links := pageLinks(v.request, pg); len(links) > 0 {
+			w.Header().Set("Link", strings.Join(links, ", "))
+		}
+	}
+
+	v.pagination = &pg
+
+	return Respond(ctx, w, data, statusCode)
+}
+
+// pageLinks returns the Link header values of the page, relative to the
+// request URI.
+func pageLinks(r *http.Request, pg Pagination) []string {
+	var links []string
+
+	link := func(rel string, param string, value string) {
+		q := r.URL.Query()
+		q.Del(QueryCursor)
+		q.Del(QueryPage)
+		q.Set(param, value)
+		if pg.Limit > 0 {
+			q.Set(QueryLimit, strconv.Itoa(pg.Limit))
+		}
+
+		links = append(links, fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.EscapedPath(), q.Encode(), rel))
+	}
+
+	if pg.PrevCursor != "" {
+		link("prev", QueryCursor, pg.PrevCursor)
+	}
+	if pg.NextCursor != "" {
+		link("next", QueryCursor, pg.NextCursor)
+	}
+
+	if pg.Page > 0 {
+		last, hasLast := pg.lastPage()
+
+		link("first", QueryPage, "1")
+		if pg.Page > 1 {
+			link("prev", QueryPage, strconv.Itoa(pg.Page-1))
+		}
+		if pg.HasMore {
+			link("next", QueryPage, strconv.Itoa(pg.Page+1))
+		}
+		if hasLast {
+			link("last", QueryPage, strconv.Itoa(last))
+		}
+	}
+
+	return links
+}
+
+// invalidQueryParamError returns the error for a malformed query parameter.
+func invalidQueryParamError(name string, expected string) error {
+	return NewRequestError(
+		fmt.Errorf("invalid query parameter %q: must be %s", name, expected),
+		http.StatusBadRequest,
+		CodeInvalidParameter,
+	)
+}
@@ -0,0 +1,198 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPagerParse(t *testing.T) {
+	p := NewPager([]byte("secret"), WithPageLimits(10, 50))
+
+	cursor, err := p.EncodeCursor(map[string]int{"after": 42})
+	if err != nil {
+		t.Fatalf("EncodeCursor failed: %v", err)
+	}
+
+	forged := NewPager([]byte("other"))
+	forgedCursor, _ := forged.EncodeCursor(map[string]int{"after": 42})
+
+	testCases := []struct {
+		name       string
+		query      string
+		expected   Page
+		withCursor bool
+		wantErr    bool
+	}{
+		{name: "Defaults", query: "", expected: Page{Limit: 10, Number: 1}},
+		{name: "Page", query: "?page=3&limit=20", expected: Page{Limit: 20, Number: 3, Offset: 40}},
+		{name: "Cursor", query: "?cursor=" + cursor, expected: Page{Limit: 10}, withCursor: true},
+		{name: "Limit Too Large", query: "?limit=51", wantErr: true},
+		{name: "Limit Zero", query: "?limit=0", wantErr: true},
+		{name: "Malformed Page", query: "?page=first", wantErr: true},
+		{name: "Negative Page", query: "?page=-1", wantErr: true},
+		{name: "Last Page", query: fmt.Sprintf("?page=%d&limit=2", math.MaxInt/2+1), expected: Page{Limit: 2, Number: math.MaxInt/2 + 1, Offset: math.MaxInt - 1}},
+		{name: "Page Overflow", query: fmt.Sprintf("?page=%d&limit=2", math.MaxInt/2+2), wantErr: true},
+		{name: "Page Overflow Max", query: fmt.Sprintf("?page=%d&limit=50", math.MaxInt), wantErr: true},
+		{name: "Cursor And Page", query: "?page=2&cursor=" + cursor, wantErr: true},
+		{name: "Forged Cursor", query: "?cursor=" + forgedCursor, wantErr: true},
+		{name: "Malformed Cursor", query: "?cursor=%21%21", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pg, err := p.Parse(httptest.NewRequest(http.MethodGet, "/sites"+tc.query, nil))
+			if tc.wantErr {
+				re := GetRequestError(err)
+				if re == nil || re.StatusCode != http.StatusBadRequest || re.Code != CodeInvalidParameter {
+					t.Fatalf("Expected a bad request error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			if pg.Limit != tc.expected.Limit || pg.Number != tc.expected.Number || pg.Offset != tc.expected.Offset {
+				t.Errorf("Expected page %+v, got %+v", tc.expected, pg)
+			}
+			if pg.HasCursor() != tc.withCursor {
+				t.Errorf("Expected HasCursor to be %v", tc.withCursor)
+			}
+		})
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	p := NewPager(nil)
+
+	cursor, err := p.EncodeCursor(map[string]int{"after": 42})
+	if err != nil {
+		t.Fatalf("EncodeCursor failed: %v", err)
+	}
+
+	pg, err := p.Parse(httptest.NewRequest(http.MethodGet, "/sites?cursor="+cursor, nil))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var got map[string]int
+	if err := pg.Cursor(&got); err != nil {
+		t.Fatalf("Cursor failed: %v", err)
+	}
+	if got["after"] != 42 {
+		t.Errorf("Expected the cursor payload to be decoded, got %v", got)
+	}
+}
+
+func TestRespondPageNotAcceptable(t *testing.T) {
+	total := int64(45)
+
+	var handlerErr error
+	api := NewWithOptions(make(chan os.Signal, 1), WithErrorHandler(func(ctx context.Context, r *http.Request, err error) {
+		handlerErr = err
+	}))
+	api.Handle(http.MethodGet, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return RespondPage(ctx, w, []string{"a", "b"}, Pagination{Limit: 10, Page: 2, Total: &total}, http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/sites?page=2&limit=10", nil)
+	req.Header.Set("Accept", "image/png")
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+
+	if re := GetRequestError(handlerErr); re == nil || re.StatusCode != http.StatusNotAcceptable {
+		t.Fatalf("Expected a not acceptable error, got %v", handlerErr)
+	}
+	if got := w.Header().Get("Link"); got != "" {
+		t.Errorf("Expected no Link header on a not acceptable request, got %s", got)
+	}
+}
+
+func TestRespondPage(t *testing.T) {
+	total := int64(45)
+
+	testCases := []struct {
+		name       string
+		target     string
+		pagination Pagination
+		links      []string
+		hasMore    bool
+	}{
+		{
+			name:       "Offset",
+			target:     "/sites?page=2&limit=10&sort=name",
+			pagination: Pagination{Limit: 10, Page: 2, Total: &total},
+			links: []string{
+				`</sites?limit=10&page=1&sort=name>; rel="first"`,
+				`</sites?limit=10&page=1&sort=name>; rel="prev"`,
+				`</sites?limit=10&page=3&sort=name>; rel="next"`,
+				`</sites?limit=10&page=5&sort=name>; rel="last"`,
+			},
+			hasMore: true,
+		},
+		{
+			name:       "Offset Last Page",
+			target:     "/sites?page=5&limit=10",
+			pagination: Pagination{Limit: 10, Page: 5, Total: &total},
+			links: []string{
+				`</sites?limit=10&page=1>; rel="first"`,
+				`</sites?limit=10&page=4>; rel="prev"`,
+				`</sites?limit=10&page=5>; rel="last"`,
+			},
+		},
+		{
+			name:       "Cursor",
+			target:     "/sites?cursor=abc",
+			pagination: Pagination{Limit: 20, NextCursor: "def", PrevCursor: "xyz"},
+			links: []string{
+				`</sites?cursor=xyz&limit=20>; rel="prev"`,
+				`</sites?cursor=def&limit=20>; rel="next"`,
+			},
+			hasMore: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := New(make(chan os.Signal, 1))
+			api.Handle(http.MethodGet, "/sites", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return RespondPage(ctx, w, []string{"a", "b"}, tc.pagination, http.StatusOK)
+			})
+
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+			if got := w.Header().Get("Link"); got != strings.Join(tc.links, ", ") {
+				t.Errorf("Expected Link header:\n%s\ngot:\n%s", strings.Join(tc.links, ", "), got)
+			}
+
+			var resp struct {
+				Data       []string   `json:"data"`
+				Pagination Pagination `json:"pagination"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to unmarshal the response: %v", err)
+			}
+
+			if len(resp.Data) != 2 {
+				t.Errorf("Expected the data to be sent, got %v", resp.Data)
+			}
+			if resp.Pagination.HasMore != tc.hasMore {
+				t.Errorf("Expected has_more to be %v", tc.hasMore)
+			}
+			if resp.Pagination.Limit != tc.pagination.Limit {
+				t.Errorf("Expected limit %d, got %d", tc.pagination.Limit, resp.Pagination.Limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// PathParam returns the value of the named path wildcard of the route
+// pattern, e.g. "id" for "/sites/{id}". It returns an empty string when the
+// wildcard does not exist.
+func PathParam(r *http.Request, name string) string {
+	return r.PathValue(name)
+}
+
+// PathInt64 returns the value of the named path wildcard parsed as a base 10
+// int64. A missing or malformed value returns a RequestError with status 400.
+func PathInt64(r *http.Request, name string) (int64, error) {
+	v := r.PathValue(name)
+	if v == "" {
+		return 0, missingPathParamError(name)
+	}
+
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, invalidPathParamError(name, "an integer")
+	}
+
+	return i, nil
+}
+
+// PathUUID returns the value of the named path wildcard validated as a UUID
+// in its canonical textual form. The value is returned lower cased. A missing
+// or malformed value returns a RequestError with status 400.
+func PathUUID(r *http.Request, name string) (string, error) {
+	v := r.PathValue(name)
+	if v == "" {
+		return "", missingPathParamError(name)
+	}
+
+	if !isUUID(v) {
+		return "", invalidPathParamError(name, "a UUID")
+	}
+
+	return strings.ToLower(v), nil
+}
+
+// isUUID checks the value is formatted as xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
+func isUUID(v string) bool {
+	if len(v) != 36 {
+		return false
+	}
+
+	for i := 0; i < len(v); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if v[i] != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdefABCDEF", rune(v[i])) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// missingPathParamError returns the error for a missing path parameter.
+func missingPathParamError(name string) error {
+	return NewRequestError(
+		fmt.Errorf("missing path parameter %q", name),
+		http.StatusBadRequest,
+		CodeInvalidParameter,
+	)
+}
+
+// invalidPathParamError returns the error for a malformed path parameter.
+func invalidPathParamError(name string, expected string) error {
+	return NewRequestError(
+		fmt.Errorf("invalid path parameter %q: must be %s", name, expected),
+		http.StatusBadRequest,
+		CodeInvalidParameter,
+	)
+}
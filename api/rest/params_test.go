@@ -0,0 +1,91 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newParamRequest returns a request with the given path wildcard set.
+func newParamRequest(name, value string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetPathValue(name, value)
+
+	return r
+}
+
+// assertParamError checks the error is a 400 request error.
+func assertParamError(t *testing.T, err error) {
+	t.Helper()
+
+	re := GetRequestError(err)
+	if re == nil {
+		t.Fatalf("Expected a request error, got %v", err)
+	}
+	if re.StatusCode != http.StatusBadRequest || re.Code != CodeInvalidParameter {
+		t.Errorf("Unexpected request error: %+v", re)
+	}
+}
+
+func TestPathParam(t *testing.T) {
+	if v := PathParam(newParamRequest("id", "abc"), "id"); v != "abc" {
+		t.Errorf("Expected 'abc', got '%s'", v)
+	}
+	if v := PathParam(newParamRequest("id", "abc"), "name"); v != "" {
+		t.Errorf("Expected empty value, got '%s'", v)
+	}
+}
+
+func TestPathInt64(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		v, err := PathInt64(newParamRequest("id", "-42"), "id")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if v != -42 {
+			t.Errorf("Expected -42, got %d", v)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := PathInt64(newParamRequest("id", "4x2"), "id")
+		assertParamError(t, err)
+	})
+
+	t.Run("Overflow", func(t *testing.T) {
+		_, err := PathInt64(newParamRequest("id", "9223372036854775808"), "id")
+		assertParamError(t, err)
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := PathInt64(newParamRequest("id", ""), "id")
+		assertParamError(t, err)
+	})
+}
+
+func TestPathUUID(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		v, err := PathUUID(newParamRequest("id", "9B2B8C64-8C2E-4F6B-A1F4-2F1D3C4B5A69"), "id")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if v != "9b2b8c64-8c2e-4f6b-a1f4-2f1d3c4b5a69" {
+			t.Errorf("Unexpected value: %s", v)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, v := range []string{"abc", "9b2b8c64x8c2e-4f6b-a1f4-2f1d3c4b5a69", "9b2b8c64-8c2e-4f6b-a1f4-2f1d3c4b5a6z"} {
+			_, err := PathUUID(newParamRequest("id", v), "id")
+			assertParamError(t, err)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := PathUUID(newParamRequest("id", ""), "id")
+		assertParamError(t, err)
+	})
+}
@@ -0,0 +1,38 @@
+package rest
+
+// Principal is the identity a request is authenticated as.
+type Principal struct {
+	// Subject identifies the principal, e.g. the sub claim of a JWT or the
+	// owner of an API key.
+	Subject string
+	// Method is the authentication method used, e.g. "jwt", "api_key" or
+	// "hmac".
+	Method string
+	// Scopes are the scopes granted to the principal.
+	Scopes []string
+	// Roles are the roles of the principal.
+	Roles []string
+	// Claims holds the raw claims of a token, if any.
+	Claims map[string]interface{}
+}
+
+// HasScope reports whether the scope is granted to the principal.
+func (p *Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+// HasRole reports whether the principal has the role.
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+// contains reports whether the value is in the list.
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
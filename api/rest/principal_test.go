@@ -0,0 +1,22 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"testing"
+)
+
+func TestPrincipal(t *testing.T) {
+	p := &Principal{
+		Scopes: []string{"sites:read", "sites:write"},
+		Roles:  []string{"admin"},
+	}
+
+	if !p.HasScope("sites:write") || p.HasScope("sites:delete") {
+		t.Errorf("Unexpected scopes check for %v", p.Scopes)
+	}
+	if !p.HasRole("admin") || p.HasRole("owner") {
+		t.Errorf("Unexpected roles check for %v", p.Roles)
+	}
+}
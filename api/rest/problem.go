@@ -0,0 +1,135 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// Problem is the RFC 7807 problem details document used for error responses.
+type Problem struct {
+	// Type is a URI reference identifying the problem type.
+	Type string `json:"type"`
+	// Title is a short, human-readable summary of the problem type.
+	Title string `json:"title"`
+	// Status is the HTTP status code of the response.
+	Status int `json:"status"`
+	// Detail is a human-readable explanation of this occurrence.
+	Detail string `json:"detail,omitempty"`
+	// Instance is a URI reference identifying this occurrence.
+	Instance string `json:"instance,omitempty"`
+	// Extensions are additional members of the document.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON implements the json.Marshaler interface. The extensions are
+// written as members of the document.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.document())
+}
+
+// MarshalXML implements the xml.Marshaler interface. The document is written
+// in the RFC 7807 namespace and the extensions are written as elements.
+func (p Problem) MarshalXML(e *xml.Encoder, _ xml.StartElement) error {
+	start := xml.StartElement{
+		Name: xml.Name{Space: "urn:ietf:rfc:7807", Local: "problem"},
+	}
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	members := p.document()
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := e.EncodeElement(members[name], xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
+
+// document returns the members of the document, the extensions included.
+func (p Problem) document() map[string]interface{} {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+
+	m["type"] = p.Type
+	m["title"] = p.Title
+	m["status"] = p.Status
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+
+	return m
+}
+
+// ProblemEncoder sends the errors as RFC 7807 application/problem+json
+// documents. Successful responses are sent without envelope.
+type ProblemEncoder struct {
+	// TypeBaseURI is prefixed to the error code to build the problem type.
+	// When empty, the type is "about:blank".
+	TypeBaseURI string
+}
+
+// Encode implements the Encoder interface.
+func (pe ProblemEncoder) Encode(ctx context.Context, data interface{}, statusCode int) (interface{}, string) {
+	v, err := GetContextValues(ctx)
+	if err != nil {
+		v = &ContextValues{}
+	}
+
+	if !v.IsError {
+		return data, "application/json"
+	}
+
+	p := Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(statusCode),
+		Status:     statusCode,
+		Extensions: make(map[string]interface{}),
+	}
+
+	if v.request != nil {
+		p.Instance = v.request.URL.RequestURI()
+	}
+
+	if v.RequestID != "" {
+		p.Extensions["request_id"] = v.RequestID
+	}
+
+	switch er := data.(type) {
+	case ErrorResponse:
+		p.Detail = er.Message
+		if er.Code != "" {
+			p.Extensions["code"] = er.Code
+			if pe.TypeBaseURI != "" {
+				p.Type = pe.TypeBaseURI + er.Code
+			}
+		}
+		if len(er.Fields) > 0 {
+			p.Extensions["fields"] = er.Fields
+		}
+
+	case nil:
+
+	default:
+		p.Detail = fmt.Sprint(er)
+	}
+
+	return p, "application/problem+json"
+}
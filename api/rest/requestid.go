@@ -0,0 +1,85 @@
+package rest
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// HeaderRequestID is the header used to read and echo the request ID.
+	HeaderRequestID = "X-Request-ID"
+
+	// HeaderTraceParent is the W3C trace context header. Its trace ID is used
+	// as request ID when no request ID header is provided.
+	HeaderTraceParent = "traceparent"
+
+	// maxRequestIDLength is the maximum length of an incoming request ID.
+	maxRequestIDLength = 128
+)
+
+// readRequestID returns the correlation ID of the request. It is read from the
+// X-Request-ID header, then from the trace ID of the traceparent header and
+// generated when both are absent or invalid.
+func readRequestID(r *http.Request) string {
+	if id := r.Header.Get(HeaderRequestID); isValidRequestID(id) {
+		return id
+	}
+
+	if id := traceID(r.Header.Get(HeaderTraceParent)); id != "" {
+		return id
+	}
+
+	return newRequestID()
+}
+
+// isValidRequestID checks the ID is not empty, bounded and only made of
+// printable ASCII characters so it is safe to echo back and log.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// traceID returns the trace ID of a traceparent header value, formatted as
+// version-traceid-parentid-flags. It returns an empty string when the value is
+// invalid.
+func traceID(traceparent string) string {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 {
+		return ""
+	}
+
+	id := parts[1]
+	if strings.Trim(id, "0") == "" {
+		return ""
+	}
+
+	for i := 0; i < len(id); i++ {
+		if !strings.ContainsRune("0123456789abcdef", rune(id[i])) {
+			return ""
+		}
+	}
+
+	return id
+}
+
+// newRequestID generates a random (version 4) UUID.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
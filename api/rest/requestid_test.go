@@ -0,0 +1,96 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	uuidRE := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	testCases := []struct {
+		name     string
+		headers  map[string]string
+		expected string
+	}{
+		{
+			name:     "Request ID Header",
+			headers:  map[string]string{HeaderRequestID: "abc-123"},
+			expected: "abc-123",
+		},
+		{
+			name:     "Trace Parent Header",
+			headers:  map[string]string{HeaderTraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			expected: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name: "Request ID Header Has Precedence",
+			headers: map[string]string{
+				HeaderRequestID:   "abc-123",
+				HeaderTraceParent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			},
+			expected: "abc-123",
+		},
+		{
+			name:    "Generated",
+			headers: nil,
+		},
+		{
+			name: "Invalid Headers",
+			headers: map[string]string{
+				HeaderRequestID:   "abc 123",
+				HeaderTraceParent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			},
+		},
+		{
+			name:    "Too Long",
+			headers: map[string]string{HeaderRequestID: strings.Repeat("a", maxRequestIDLength+1)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var fromCtx string
+			api := New(make(chan os.Signal, 1))
+			api.Handle(http.MethodGet, "/", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				fromCtx = GetRequestID(ctx)
+				return Respond(ctx, w, "ok", http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			api.ServeHTTP(rr, req)
+
+			echoed := rr.Header().Get(HeaderRequestID)
+			if tc.expected != "" && echoed != tc.expected {
+				t.Errorf("Expected request ID %q, got %q", tc.expected, echoed)
+			}
+			if tc.expected == "" && !uuidRE.MatchString(echoed) {
+				t.Errorf("Expected a generated request ID, got %q", echoed)
+			}
+			if fromCtx != echoed {
+				t.Errorf("Expected request ID %q in context, got %q", echoed, fromCtx)
+			}
+
+			var resp Response
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if resp.RequestID != echoed {
+				t.Errorf("Expected request ID %q in response, got %q", echoed, resp.RequestID)
+			}
+		})
+	}
+}
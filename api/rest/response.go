@@ -2,30 +2,41 @@ package rest
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
+	"strconv"
 )
 
 // Response is the form used for API responses for success in the API.
 type Response struct {
+	XMLName struct{} `json:"-" xml:"response" msgpack:"-" cbor:"-"`
+
 	// Success
 	//
-	Success bool `json:"success"`
+	Success bool `json:"success" xml:"success"`
 
 	// Timestamp
 	//
 	// example: 1234567
-	Timestamp int64 `json:"timestamp"`
+	Timestamp int64 `json:"timestamp" xml:"timestamp"`
 
 	// Data
 	// in: body
-	Data interface{} `json:"data,omitempty"`
+	Data interface{} `json:"data,omitempty" xml:"data,omitempty"`
 
 	// Errors
 	// in: body
-	Errors interface{} `json:"errors,omitempty"`
+	Errors interface{} `json:"errors,omitempty" xml:"errors,omitempty"`
+
+	// Pagination
+	// in: body
+	Pagination *Pagination `json:"pagination,omitempty" xml:"pagination,omitempty"`
+
+	// RequestID
+	//
+	// example: 9b2b8c64-8c2e-4f6b-a1f4-2f1d3c4b5a69
+	RequestID string `json:"request_id,omitempty" xml:"request_id,omitempty"`
 }
 
 // Respond constructs and sends an HTTP response to the client.
@@ -47,35 +58,77 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statu
 		return ErrMissingContext
 	}
 
+	// Select the codec accepted by the client. The errors are sent with the
+	// default codec when none is acceptable.
+	codecs, codec, ok := negotiateCodec(v)
+	if !ok {
+		if !v.IsError {
+			return NewRequestError(
+				errors.New("none of the accepted media types is supported"),
+				http.StatusNotAcceptable,
+				CodeNotAcceptable,
+			)
+		}
+
+		codec = codecs.Default()
+	}
+
 	// Set the status code for the request logger middleware in the context.
 	err = SetStatusCode(ctx, statusCode)
 	if err != nil {
 		return err
 	}
 
-	// Create the response object to be sent back to the client.
-	r := Response{
-		Success:   !v.IsError,
-		Timestamp: time.Now().UTC().Unix(),
+	// Build the document for the response format used by the request.
+	enc := v.encoder
+	if enc == nil {
+		enc = EnvelopeEncoder{}
 	}
 
-	// Check if the data is an error or not
-	// If it is not an error, then set the data
-	// If it is an error, then set the error
-	if !v.IsError {
-		r.Data = data
-	} else {
-		r.Errors = data
-	}
+	doc, ct := enc.Encode(ctx, data, statusCode)
 
-	// Convert the response to json
-	jd, err := json.Marshal(r)
+	// Convert the response to the negotiated format
+	jd, err := codec.Marshal(doc)
 	if err != nil {
+		if errors.Is(err, ErrUnsupportedData) && !v.IsError {
+			return NewRequestError(err, http.StatusNotAcceptable, CodeNotAcceptable)
+		}
+
 		return fmt.Errorf("marshal fail: %w", err)
 	}
 
 	// set the content type now that we know there was no marshal error
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType(ct, codec))
+	if len(codecs.codecs) > 1 {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	// Tag the representation and answer the conditional reads.
+	if v.etags != NoETags && !v.IsError && statusCode == http.StatusOK {
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			etag, err = computeETag(v, codec, data)
+			if err != nil {
+				return err
+			}
+		}
+
+		if v.request != nil && (v.request.Method == http.MethodGet || v.request.Method == http.MethodHead) {
+			lastModified, _ := http.ParseTime(w.Header().Get("Last-Modified"))
+
+			switch evaluatePreconditions(v.request, etag, lastModified) {
+			case http.StatusNotModified:
+				w.Header().Set("ETag", etag)
+				return notModified(ctx, w)
+			case http.StatusPreconditionFailed:
+				return NewRequestError(ErrPreconditionFailed, http.StatusPreconditionFailed, CodePreconditionFailed)
+			}
+		}
+
+		w.Header().Set("ETag", etag)
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(jd)))
 	w.WriteHeader(statusCode)
 
 	// Send the result back to the client
@@ -85,3 +138,24 @@ func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statu
 
 	return nil
 }
+
+// negotiateCodec returns the codecs of the request and the codec accepted by
+// the client, the default one when there is no request. It returns false when
+// none is acceptable.
+func negotiateCodec(v *ContextValues) (*Codecs, Codec, bool) {
+	codecs := v.codecs
+	if codecs == nil {
+		codecs = defaultCodecs
+	}
+
+	if v.request == nil {
+		return codecs, codecs.Default(), true
+	}
+
+	codec, ok := codecs.Negotiate(v.request.Header.Get("Accept"))
+	if !ok {
+		return codecs, codecs.Default(), false
+	}
+
+	return codecs, codec, true
+}
@@ -1,10 +1,20 @@
 // Package rest provides the wrapper for routing purposes.
 //
 // It provides a way to set the routes and the handlers for the application.
+// It provides a way to group the routes under a path prefix.
 // It provides a way to set the middleware for the application.
+// It provides a way to decode and validate the request body.
 // It provides a way to respond to the client.
+// It provides a way to paginate the collections sent to the client.
+// It provides a way to tag the responses with ETags and answer the
+// conditional requests.
+// It provides a way to stream large collections to the client.
+// It provides a way to push Server-Sent Events to the client.
 // It provides a way to respond with an error to the client.
+// It provides a way to choose the response format, e.g. RFC 7807 problems.
 // It provides a way to set and get the context values.
+// It provides a way to run the server with a graceful shutdown.
+// It provides a way to generate the OpenAPI 3.1 document of the routes.
 
 package rest
 
@@ -12,7 +22,9 @@ import (
 	"context"
 	"net/http"
 	"os"
+	"strings"
 	"syscall"
+	"time"
 )
 
 // A Handler is a type that handles a http request within the framework.
@@ -20,9 +32,16 @@ type Handler func(ctx context.Context, w http.ResponseWriter, r *http.Request) e
 
 // API is the handler for api package.
 type API struct {
-	shutdown chan os.Signal
-	mux      *http.ServeMux
-	mw       []Middleware
+	shutdown     chan os.Signal
+	mux          *http.ServeMux
+	mw           []Middleware
+	errorHandler ErrorHandler
+	encoder      Encoder
+	codecs       *Codecs
+	etags        ETagMode
+	hooks        []ShutdownHook
+	routes       map[string]*route
+	documented   []documentedRoute
 }
 
 // New creates an API struct with provided middleware.
@@ -30,11 +49,28 @@ type API struct {
 //	shutdown: channel to signal shutdown
 //	mw: list of middleware to execute on each request
 func New(shutdown chan os.Signal, mw ...Middleware) *API {
-	return &API{
-		shutdown: shutdown,
-		mux:      http.NewServeMux(),
-		mw:       mw,
+	return NewWithOptions(shutdown, WithMiddleware(mw...))
+}
+
+// NewWithOptions creates an API struct configured with the provided options.
+//
+//	shutdown: channel to signal shutdown
+//	opts: list of options to configure the API
+func NewWithOptions(shutdown chan os.Signal, opts ...Option) *API {
+	a := &API{
+		shutdown:     shutdown,
+		mux:          http.NewServeMux(),
+		errorHandler: defaultErrorHandler,
+		encoder:      EnvelopeEncoder{},
+		codecs:       defaultCodecs,
+		routes:       make(map[string]*route),
+	}
+
+	for _, opt := range opts {
+		opt.apply(a)
 	}
+
+	return a
 }
 
 // SignalShutdown is used to gracefully shut down the app when an integrity
@@ -46,29 +82,162 @@ func (a *API) SignalShutdown() {
 // Handle sets a handler function for a given HTTP method and path pair
 // to the application server mux.
 func (a *API) Handle(method string, path string, handler Handler, mw ...Middleware) {
+	a.handle(method, path, Operation{}, handler, nil, mw)
+}
+
+// handle registers the handler wrapped by the route, group and general
+// middleware, in that order from the innermost, and records the operation
+// for the OpenAPI document.
+//
+// The first route of a path also registers an OPTIONS handler for the path,
+// so OPTIONS requests, e.g. the CORS preflight requests, go through the
+// general middleware instead of being rejected by the mux. It answers with
+// the allowed methods unless the application registers its own OPTIONS
+// handler for the path. A route without a method handles the OPTIONS requests
+// itself, no OPTIONS handler is registered for its path.
+func (a *API) handle(method string, path string, op Operation, handler Handler, groupMW []Middleware, routeMW []Middleware) {
+	a.documented = append(a.documented, documentedRoute{method: method, path: path, op: op})
+
 	// First wrap handler specific middleware around this handler
-	handler = wrapMiddleware(mw, handler)
+	handler = wrapMiddleware(routeMW, handler)
+
+	// Add the group's middleware to the handler chain.
+	handler = wrapMiddleware(groupMW, handler)
+
+	rt, ok := a.routes[path]
+	if !ok {
+		rt = &route{}
+		a.routes[path] = rt
+	}
+
+	switch method {
+	case http.MethodOptions:
+		rt.options = handler
+
+		// The automatic OPTIONS handler dispatches to the application one.
+		if rt.auto {
+			return
+		}
+
+	case "":
+		rt.any = handler
+
+	default:
+		rt.methods = append(rt.methods, method)
+
+		if !rt.auto && rt.options == nil && rt.any == nil {
+			rt.auto = true
+			a.mux.Handle(http.MethodOptions+" "+path, a.serve(path, wrapMiddleware(a.mw, rt.serveOptions)))
+		}
+	}
 
 	// Add the package's general middleware to the handler chain.
-	handler = wrapMiddleware(a.mw, handler)
+	a.mux.Handle(method+" "+path, a.serve(path, wrapMiddleware(a.mw, handler)))
+}
 
+// serve returns the http handler running the handler chain of the route
+// pattern path.
+func (a *API) serve(path string, handler Handler) http.Handler {
 	h1 := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
 		// Set the context with the required values to
 		// process the request.
-		ctx := context.WithValue(r.Context(), key, &ContextValues{})
+		v := &ContextValues{
+			encoder:      a.encoder,
+			codecs:       a.codecs,
+			etags:        a.etags,
+			errorHandler: a.errorHandler,
+		}
+		ctx := context.WithValue(r.Context(), key, v)
+
+		// Make the values available from the request as well.
+		r = r.WithContext(ctx)
+		v.request = r
+
+		// Record what is written to the client, whatever the way.
+		w = &responseWriter{
+			ResponseWriter: w,
+			v:              v,
+			start:          start,
+		}
 
 		// Register this path
 		_ = SetPath(ctx, path)
 
+		// Correlate the request and echo the ID back to the client.
+		requestID := readRequestID(r)
+		_ = SetRequestID(ctx, requestID)
+		w.Header().Set(HeaderRequestID, requestID)
+
 		// Execute the handler.
 		//
-		// If there is an error, then shutdown the server.
+		// If there is a shutdown error, then shutdown the server. Any other
+		// error is handed over to the error handler.
 		if err := handler(ctx, w, r); err != nil {
-			a.SignalShutdown()
+			if IsShutdown(err) {
+				a.SignalShutdown()
+				return
+			}
+
+			a.errorHandler(ctx, r, err)
 		}
 	})
 
-	a.mux.Handle(method+" "+path, h1)
+	return h1
+}
+
+// route holds the handlers registered for a path.
+type route struct {
+	// methods are the methods registered for the path, OPTIONS excluded.
+	methods []string
+	// options is the OPTIONS handler registered by the application.
+	options Handler
+	// any is the handler registered without a method.
+	any Handler
+	// auto is set once the automatic OPTIONS handler is registered.
+	auto bool
+}
+
+// serveOptions answers an OPTIONS request with the allowed methods, unless
+// the application registered its own OPTIONS handler or a handler without a
+// method.
+func (rt *route) serveOptions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	if rt.options != nil {
+		return rt.options(ctx, w, r)
+	}
+	if rt.any != nil {
+		return rt.any(ctx, w, r)
+	}
+
+	w.Header().Set("Allow", strings.Join(rt.allow(), ", "))
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// allow returns the methods allowed for the path. HEAD is allowed along GET
+// as the mux serves it with the GET handler.
+func (rt *route) allow() []string {
+	methods := make([]string, 0, len(rt.methods)+2)
+	seen := make(map[string]bool, len(rt.methods)+2)
+
+	add := func(method string) {
+		if !seen[method] {
+			seen[method] = true
+			methods = append(methods, method)
+		}
+	}
+
+	for _, method := range rt.methods {
+		add(method)
+		if method == http.MethodGet {
+			add(http.MethodHead)
+		}
+	}
+	add(http.MethodOptions)
+
+	return methods
 }
 
 // ServeHTTP implements the http.Handler interface. It's the entry point for
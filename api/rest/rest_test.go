@@ -6,6 +6,7 @@ package rest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -54,6 +55,27 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewWithOptions(t *testing.T) {
+	shutdown := make(chan os.Signal, 1)
+	called := false
+	eh := func(ctx context.Context, r *http.Request, err error) {
+		called = true
+	}
+
+	api := NewWithOptions(shutdown, WithMiddleware(mockMiddleware, mockMiddleware), WithErrorHandler(eh))
+	if api.shutdown != shutdown {
+		t.Error("shutdown channel not assigned correctly")
+	}
+	if len(api.mw) != 2 {
+		t.Error("middleware not added correctly")
+	}
+
+	api.errorHandler(context.Background(), httptest.NewRequest("GET", "/", nil), errors.New("error"))
+	if !called {
+		t.Error("error handler not assigned correctly")
+	}
+}
+
 func TestSignalShutdown(t *testing.T) {
 	shutdown := make(chan os.Signal, 1)
 	api := New(shutdown)
@@ -152,6 +174,56 @@ func TestAPI_Handle(t *testing.T) {
 	})
 }
 
+func TestAPI_HandleErrors(t *testing.T) {
+	t.Run("Error Is Dropped", func(t *testing.T) {
+		shutdown := make(chan os.Signal, 1)
+		var handled error
+		api := NewWithOptions(shutdown, WithErrorHandler(func(ctx context.Context, r *http.Request, err error) {
+			handled = err
+		}))
+
+		handlerErr := errors.New("write fail")
+		api.Handle("GET", "/error", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return handlerErr
+		})
+		api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/error", nil))
+
+		if handled != handlerErr {
+			t.Errorf("Expected error handler to receive %v, got %v", handlerErr, handled)
+		}
+		select {
+		case sig := <-shutdown:
+			t.Errorf("Unexpected shutdown signal %v", sig)
+		default:
+		}
+	})
+
+	t.Run("Shutdown Error", func(t *testing.T) {
+		shutdown := make(chan os.Signal, 1)
+		handled := false
+		api := NewWithOptions(shutdown, WithErrorHandler(func(ctx context.Context, r *http.Request, err error) {
+			handled = true
+		}))
+
+		api.Handle("GET", "/shutdown", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			return NewShutdownError("integrity issue")
+		})
+		api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/shutdown", nil))
+
+		if handled {
+			t.Error("Expected shutdown error to bypass the error handler")
+		}
+		select {
+		case sig := <-shutdown:
+			if sig != syscall.SIGTERM {
+				t.Errorf("Expected signal %v, got %v", syscall.SIGTERM, sig)
+			}
+		default:
+			t.Error("Expected shutdown signal")
+		}
+	})
+}
+
 func TestAPI_ServeHTTP(t *testing.T) {
 	req, err := http.NewRequest("GET", "/", nil)
 	if err != nil {
@@ -173,3 +245,85 @@ func TestAPI_ServeHTTP(t *testing.T) {
 			status, http.StatusOK)
 	}
 }
+
+func TestAPI_HandleOptions(t *testing.T) {
+	var calls []string
+	recorder := func(next Handler) Handler {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			calls = append(calls, r.Method)
+			return next(ctx, w, r)
+		}
+	}
+
+	api := New(make(chan os.Signal, 1), recorder)
+	api.Handle(http.MethodGet, "/sites/{id}", mockHandler)
+	api.Handle(http.MethodDelete, "/sites/{id}", mockHandler)
+
+	// An application OPTIONS handler registered before or after the other
+	// methods replaces the automatic one.
+	custom := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+	api.Handle(http.MethodOptions, "/before", custom)
+	api.Handle(http.MethodGet, "/before", mockHandler)
+	api.Handle(http.MethodGet, "/after", mockHandler)
+	api.Handle(http.MethodOptions, "/after", custom)
+
+	rr := httptest.NewRecorder()
+	api.ServeHTTP(rr, httptest.NewRequest(http.MethodOptions, "/sites/42", nil))
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	if got, want := rr.Header().Get("Allow"), "GET, HEAD, DELETE, OPTIONS"; got != want {
+		t.Errorf("Expected Allow header %q, got %q", want, got)
+	}
+	if len(calls) != 1 || calls[0] != http.MethodOptions {
+		t.Errorf("Expected the general middleware to run, got %v", calls)
+	}
+
+	for _, path := range []string{"/before", "/after"} {
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodOptions, path, nil))
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", path, rr.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestAPI_HandleOptionsWithoutMethod(t *testing.T) {
+	var methods []string
+	anyHandler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		methods = append(methods, r.Method)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	// A route without a method receives the OPTIONS requests, whether it is
+	// registered before or after the other methods of the path.
+	api := New(make(chan os.Signal, 1))
+	api.Handle("", "/any", anyHandler)
+	api.Handle("", "/mixed", anyHandler)
+	api.Handle(http.MethodGet, "/mixed", mockHandler)
+	api.Handle(http.MethodGet, "/late", mockHandler)
+	api.Handle("", "/late", anyHandler)
+
+	for _, path := range []string{"/any", "/mixed", "/late"} {
+		methods = nil
+
+		rr := httptest.NewRecorder()
+		api.ServeHTTP(rr, httptest.NewRequest(http.MethodOptions, path, nil))
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", path, rr.Code, http.StatusOK)
+		}
+		if allow := rr.Header().Get("Allow"); allow != "" {
+			t.Errorf("%s: Expected no automatic Allow header, got %q", path, allow)
+		}
+		if len(methods) != 1 || methods[0] != http.MethodOptions {
+			t.Errorf("%s: Expected the handler to receive the OPTIONS request, got %v", path, methods)
+		}
+	}
+}
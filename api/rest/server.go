@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultReadTimeout is the maximum duration for reading the entire
+	// request, including the body.
+	defaultReadTimeout = 10 * time.Second
+	// defaultReadHeaderTimeout is the amount of time allowed to read the
+	// request headers.
+	defaultReadHeaderTimeout = 5 * time.Second
+	// defaultWriteTimeout is the maximum duration before timing out writes of
+	// the response.
+	defaultWriteTimeout = 30 * time.Second
+	// defaultIdleTimeout is the maximum amount of time to wait for the next
+	// request when keep-alives are enabled.
+	defaultIdleTimeout = 120 * time.Second
+	// defaultShutdownTimeout is the time given to the in-flight requests and
+	// the shutdown hooks to complete.
+	defaultShutdownTimeout = 20 * time.Second
+)
+
+// ShutdownHook is a function executed by Run once the server stopped
+// accepting requests, e.g. to flush the metrics or close a pubsub client.
+type ShutdownHook func(ctx context.Context) error
+
+// ServerOption configures the http.Server started by Run.
+type ServerOption interface {
+	apply(*serverOptions)
+}
+
+// serverOptions holds the settings of the http.Server started by Run.
+type serverOptions struct {
+	readTimeout       time.Duration
+	readHeaderTimeout time.Duration
+	writeTimeout      time.Duration
+	idleTimeout       time.Duration
+	shutdownTimeout   time.Duration
+}
+
+type serverOptionFunc func(*serverOptions)
+
+func (f serverOptionFunc) apply(o *serverOptions) { f(o) }
+
+// WithReadTimeout sets the maximum duration for reading the entire request.
+func WithReadTimeout(d time.Duration) ServerOption {
+	return serverOptionFunc(func(o *serverOptions) {
+		o.readTimeout = d
+	})
+}
+
+// WithReadHeaderTimeout sets the amount of time allowed to read the request
+// headers.
+func WithReadHeaderTimeout(d time.Duration) ServerOption {
+	return serverOptionFunc(func(o *serverOptions) {
+		o.readHeaderTimeout = d
+	})
+}
+
+// WithWriteTimeout sets the maximum duration before timing out writes of the
+// response.
+func WithWriteTimeout(d time.Duration) ServerOption {
+	return serverOptionFunc(func(o *serverOptions) {
+		o.writeTimeout = d
+	})
+}
+
+// WithIdleTimeout sets the maximum amount of time to wait for the next
+// request when keep-alives are enabled.
+func WithIdleTimeout(d time.Duration) ServerOption {
+	return serverOptionFunc(func(o *serverOptions) {
+		o.idleTimeout = d
+	})
+}
+
+// WithShutdownTimeout sets the time given to the in-flight requests and the
+// shutdown hooks to complete.
+func WithShutdownTimeout(d time.Duration) ServerOption {
+	return serverOptionFunc(func(o *serverOptions) {
+		o.shutdownTimeout = d
+	})
+}
+
+// OnShutdown registers a hook executed by Run during the shutdown. The hooks
+// are executed in the reverse order of their registration.
+func (a *API) OnShutdown(hook ShutdownHook) {
+	a.hooks = append(a.hooks, hook)
+}
+
+// Run starts an http.Server serving the API on the given address and blocks
+// until the context is done, a signal is received on the shutdown channel of
+// the API or the server fails. On shutdown, the in-flight requests are drained
+// and the shutdown hooks are executed within the shutdown timeout.
+func (a *API) Run(ctx context.Context, addr string, opts ...ServerOption) error {
+	o := serverOptions{
+		readTimeout:       defaultReadTimeout,
+		readHeaderTimeout: defaultReadHeaderTimeout,
+		writeTimeout:      defaultWriteTimeout,
+		idleTimeout:       defaultIdleTimeout,
+		shutdownTimeout:   defaultShutdownTimeout,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           a,
+		ReadTimeout:       o.readTimeout,
+		ReadHeaderTimeout: o.readHeaderTimeout,
+		WriteTimeout:      o.writeTimeout,
+		IdleTimeout:       o.idleTimeout,
+	}
+
+	// Make a channel to listen for errors coming from the listener. Use a
+	// buffered channel so the goroutine can exit if we don't collect this
+	// error.
+	serverErrors := make(chan error, 1)
+
+	go func() {
+		serverErrors <- srv.ListenAndServe()
+	}()
+
+	// Blocking main and waiting for shutdown.
+	select {
+	case err := <-serverErrors:
+		return fmt.Errorf("server error: %w", err)
+
+	case <-a.shutdown:
+	case <-ctx.Done():
+	}
+
+	// Give outstanding requests a deadline for completion.
+	sctx, cancel := context.WithTimeout(context.Background(), o.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+
+	// Asking listener to shut down and shed load.
+	if err := srv.Shutdown(sctx); err != nil {
+		_ = srv.Close()
+		errs = append(errs, fmt.Errorf("could not stop server gracefully: %w", err))
+	}
+
+	for i := len(a.hooks) - 1; i >= 0; i-- {
+		if err := a.hooks[i](sctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,148 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// freeAddr returns a local address which is free to listen on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+// waitForServer waits until the server accepts connections.
+func waitForServer(t *testing.T, addr string) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatal("server did not start")
+}
+
+func TestAPI_Run(t *testing.T) {
+	t.Run("Shutdown Signal", func(t *testing.T) {
+		shutdown := make(chan os.Signal, 1)
+		api := New(shutdown)
+
+		started := make(chan struct{})
+		api.Handle(http.MethodGet, "/slow", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+			return nil
+		})
+
+		var hooks []string
+		api.OnShutdown(func(ctx context.Context) error {
+			hooks = append(hooks, "first")
+			return nil
+		})
+		api.OnShutdown(func(ctx context.Context) error {
+			hooks = append(hooks, "second")
+			return nil
+		})
+
+		addr := freeAddr(t)
+		runErr := make(chan error, 1)
+		go func() {
+			runErr <- api.Run(context.Background(), addr)
+		}()
+		waitForServer(t, addr)
+
+		// Start a request and shutdown while it is in-flight.
+		status := make(chan int, 1)
+		go func() {
+			res, err := http.Get("http://" + addr + "/slow")
+			if err != nil {
+				status <- 0
+				return
+			}
+			res.Body.Close()
+			status <- res.StatusCode
+		}()
+
+		<-started
+		shutdown <- syscall.SIGTERM
+
+		select {
+		case err := <-runErr:
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("Run did not return")
+		}
+
+		if code := <-status; code != http.StatusOK {
+			t.Errorf("Expected in-flight request to be drained, got status %d", code)
+		}
+		if len(hooks) != 2 || hooks[0] != "second" || hooks[1] != "first" {
+			t.Errorf("Unexpected hooks execution: %v", hooks)
+		}
+	})
+
+	t.Run("Context Done", func(t *testing.T) {
+		api := New(make(chan os.Signal, 1))
+
+		hookErr := errors.New("hook failure")
+		api.OnShutdown(func(ctx context.Context) error {
+			return hookErr
+		})
+
+		addr := freeAddr(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		runErr := make(chan error, 1)
+		go func() {
+			runErr <- api.Run(ctx, addr, WithShutdownTimeout(time.Second))
+		}()
+		waitForServer(t, addr)
+
+		cancel()
+
+		select {
+		case err := <-runErr:
+			if !errors.Is(err, hookErr) {
+				t.Errorf("Expected hook error, got %v", err)
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("Run did not return")
+		}
+	})
+
+	t.Run("Server Error", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+
+		api := New(make(chan os.Signal, 1))
+		if err := api.Run(context.Background(), l.Addr().String()); err == nil {
+			t.Error("Expected an error when the address is in use")
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderLastEventID is the header sent by the browsers when they reconnect
+	// to an event stream.
+	HeaderLastEventID = "Last-Event-ID"
+
+	// defaultHeartbeat is the default interval between two heartbeats.
+	defaultHeartbeat = 15 * time.Second
+)
+
+// Event is a Server-Sent Event.
+type Event struct {
+	// ID is the event ID, sent back by the client in the Last-Event-ID
+	// header when it reconnects.
+	ID string
+	// Event is the event type, "message" when empty.
+	Event string
+	// Data is the payload of the event. Strings and byte slices are sent as
+	// is, any other value is sent as JSON.
+	Data interface{}
+	// Retry is the reconnection time requested to the client.
+	Retry time.Duration
+}
+
+// SSEOption configures the event stream created by NewSSE.
+type SSEOption interface {
+	apply(*SSE)
+}
+
+type sseOptionFunc func(*SSE)
+
+func (f sseOptionFunc) apply(s *SSE) { f(s) }
+
+// WithHeartbeat sets the interval between two heartbeats sent by Run to keep
+// the connection alive.
+func WithHeartbeat(d time.Duration) SSEOption {
+	return sseOptionFunc(func(s *SSE) {
+		if d > 0 {
+			s.heartbeat = d
+		}
+	})
+}
+
+// SSE writes Server-Sent Events to the client.
+type SSE struct {
+	w           http.ResponseWriter
+	rc          *http.ResponseController
+	lastEventID string
+	heartbeat   time.Duration
+}
+
+// NewSSE starts an event stream on the response. It sends the headers right
+// away and records the status code in the context for the middleware. The
+// write deadline of the server is removed as the stream is long-lived.
+func NewSSE(ctx context.Context, w http.ResponseWriter, r *http.Request, opts ...SSEOption) (*SSE, error) {
+	s := &SSE{
+		w:           w,
+		rc:          http.NewResponseController(w),
+		lastEventID: r.Header.Get(HeaderLastEventID),
+		heartbeat:   defaultHeartbeat,
+	}
+
+	for _, opt := range opts {
+		opt.apply(s)
+	}
+
+	_ = s.rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	if err := SetStatusCode(ctx, http.StatusOK); err != nil {
+		return nil, err
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if err := s.rc.Flush(); err != nil {
+		return nil, fmt.Errorf("streaming not supported: %w", err)
+	}
+
+	return s, nil
+}
+
+// LastEventID returns the ID of the last event received by the client before
+// it reconnected, empty on the first connection.
+func (s *SSE) LastEventID() string {
+	return s.lastEventID
+}
+
+// Send writes the event to the client and flushes it.
+func (s *SSE) Send(e Event) error {
+	var buf bytes.Buffer
+
+	if e.ID != "" {
+		buf.WriteString("id: " + sanitizeEventField(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		buf.WriteString("event: " + sanitizeEventField(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		buf.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+
+	var data string
+	switch d := e.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		b, err := json.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("marshal fail: %w", err)
+		}
+		data = string(b)
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		buf.WriteString("data: " + line + "\n")
+	}
+	buf.WriteString("\n")
+
+	return s.write(buf.Bytes())
+}
+
+// Run sends the events received from the channel until it is closed or the
+// context is done. Heartbeats are sent when no event was sent during the
+// heartbeat interval. It returns nil when the client goes away.
+func (s *SSE) Run(ctx context.Context, events <-chan Event) error {
+	ticker := time.NewTicker(s.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			if err := s.Send(e); err != nil {
+				return err
+			}
+			ticker.Reset(s.heartbeat)
+
+		case <-ticker.C:
+			if err := s.write([]byte(": heartbeat\n\n")); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// write writes the bytes to the client and flushes them.
+func (s *SSE) write(b []byte) error {
+	if _, err := s.w.Write(b); err != nil {
+		return fmt.Errorf("write fail: %w", err)
+	}
+
+	if err := s.rc.Flush(); err != nil {
+		return fmt.Errorf("flush fail: %w", err)
+	}
+
+	return nil
+}
+
+// sanitizeEventField removes the line breaks which would end the field.
+func sanitizeEventField(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
@@ -0,0 +1,123 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSE_Send(t *testing.T) {
+	v := &ContextValues{}
+	ctx := context.WithValue(context.Background(), key, v)
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/jobs/1/events", nil)
+	req.Header.Set(HeaderLastEventID, "41")
+
+	s, err := NewSSE(ctx, rr, req)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if s.LastEventID() != "41" {
+		t.Errorf("Expected last event ID '41', got '%s'", s.LastEventID())
+	}
+	if v.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %v in context, got %v", http.StatusOK, v.StatusCode)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected Content-Type 'text/event-stream', got '%s'", ct)
+	}
+
+	events := []Event{
+		{ID: "42", Event: "progress", Data: map[string]int{"percent": 50}, Retry: 3 * time.Second},
+		{Data: "line 1\nline 2"},
+		{ID: "4\n3", Event: "done"},
+	}
+	for _, e := range events {
+		if err := s.Send(e); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	expected := "id: 42\nevent: progress\nretry: 3000\ndata: {\"percent\":50}\n\n" +
+		"data: line 1\ndata: line 2\n\n" +
+		"id: 43\nevent: done\ndata: \n\n"
+	if body := rr.Body.String(); body != expected {
+		t.Errorf("Unexpected body:\ngot  %q\nwant %q", body, expected)
+	}
+}
+
+func TestSSE_Run(t *testing.T) {
+	events := make(chan Event)
+
+	api := New(make(chan os.Signal, 1))
+	api.Handle(http.MethodGet, "/jobs/{id}/events", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		s, err := NewSSE(ctx, w, r, WithHeartbeat(20*time.Millisecond))
+		if err != nil {
+			return err
+		}
+
+		return s.Run(ctx, events)
+	})
+
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/jobs/1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(res.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+
+	// readUntil reads the lines until the expected one.
+	readUntil := func(expected string) {
+		t.Helper()
+
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("Stream ended before %q", expected)
+				}
+				if line == expected {
+					return
+				}
+			case <-timeout:
+				t.Fatalf("Timed out waiting for %q", expected)
+			}
+		}
+	}
+
+	readUntil(": heartbeat")
+
+	events <- Event{ID: "1", Data: "started"}
+	readUntil("id: 1")
+	readUntil("data: started")
+
+	// The stream ends when the channel is closed.
+	close(events)
+	for range lines {
+	}
+
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/event-stream") {
+		t.Errorf("Unexpected Content-Type: %s", res.Header.Get("Content-Type"))
+	}
+}
@@ -0,0 +1,341 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultFlushEvery is the default number of items written between flushes.
+const defaultFlushEvery = 100
+
+// Iterator iterates over the items of a streamed collection.
+type Iterator interface {
+	// Next advances to the next item. It returns false when the iteration is
+	// over or failed.
+	Next(ctx context.Context) bool
+	// Value returns the current item.
+	Value() interface{}
+	// Err returns the error which stopped the iteration, if any.
+	Err() error
+}
+
+// chanIterator iterates over the values received from a channel.
+type chanIterator[T any] struct {
+	items <-chan T
+	errs  <-chan error
+	value T
+	err   error
+}
+
+// ChannelIterator returns an Iterator over the items received from the channel
+// until it is closed. An error received from errs stops the iteration, errs
+// can be nil.
+func ChannelIterator[T any](items <-chan T, errs <-chan error) Iterator {
+	return &chanIterator[T]{
+		items: items,
+		errs:  errs,
+	}
+}
+
+// Next implements the Iterator interface.
+func (it *chanIterator[T]) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+
+	select {
+	case <-ctx.Done():
+		it.err = ctx.Err()
+		return false
+
+	case err := <-it.errs:
+		// A closed error channel is not an error.
+		if err == nil {
+			it.errs = nil
+			return it.Next(ctx)
+		}
+
+		it.err = err
+		return false
+
+	case v, ok := <-it.items:
+		if !ok {
+			return false
+		}
+
+		it.value = v
+		return true
+	}
+}
+
+// Value implements the Iterator interface.
+func (it *chanIterator[T]) Value() interface{} {
+	return it.value
+}
+
+// Err implements the Iterator interface.
+func (it *chanIterator[T]) Err() error {
+	return it.err
+}
+
+// StreamFormat is the format of a streamed response.
+type StreamFormat int
+
+const (
+	// StreamJSON streams the standard envelope with the items in the data
+	// array. The success member is written last so it reflects failures.
+	StreamJSON StreamFormat = iota
+	// StreamNDJSON streams one standard envelope per item, separated by new
+	// lines. A failure is written as a last envelope with the errors.
+	StreamNDJSON
+)
+
+// StreamOption configures RespondStream.
+type StreamOption interface {
+	apply(*streamOptions)
+}
+
+// streamOptions holds the settings of RespondStream.
+type streamOptions struct {
+	format     StreamFormat
+	flushEvery int
+}
+
+type streamOptionFunc func(*streamOptions)
+
+func (f streamOptionFunc) apply(o *streamOptions) { f(o) }
+
+// WithStreamFormat sets the format of the streamed response, StreamJSON by
+// default.
+func WithStreamFormat(format StreamFormat) StreamOption {
+	return streamOptionFunc(func(o *streamOptions) {
+		o.format = format
+	})
+}
+
+// WithFlushEvery sets the number of items written between flushes.
+func WithFlushEvery(n int) StreamOption {
+	return streamOptionFunc(func(o *streamOptions) {
+		if n > 0 {
+			o.flushEvery = n
+		}
+	})
+}
+
+// RespondStream writes the items of the iterator to the client as they come,
+// without holding the whole collection in memory. The response is flushed
+// regularly and the stream stops when the context is done.
+//
+// An error happening before the first item is returned as is, so it can be
+// handled like any other error. Once the stream started, the status code is
+// already sent: the failure is written in the stream, the request is flagged
+// as an error in the context and nil is returned.
+func RespondStream(ctx context.Context, w http.ResponseWriter, it Iterator, opts ...StreamOption) error {
+	v, err := GetContextValues(ctx)
+	if err != nil {
+		return ErrMissingContext
+	}
+
+	o := streamOptions{
+		format:     StreamJSON,
+		flushEvery: defaultFlushEvery,
+	}
+
+	for _, opt := range opts {
+		opt.apply(&o)
+	}
+
+	s := &streamer{
+		ctx:  ctx,
+		w:    w,
+		rc:   http.NewResponseController(w),
+		v:    v,
+		opts: o,
+	}
+
+	// The stream may outlast the write timeout of the server.
+	if err := s.rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return fmt.Errorf("set write deadline fail: %w", err)
+	}
+
+	for it.Next(ctx) {
+		if err := s.item(it.Value()); err != nil {
+			return s.fail(err)
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return s.fail(err)
+	}
+
+	return s.end()
+}
+
+// streamer writes the parts of a streamed response.
+type streamer struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	v       *ContextValues
+	opts    streamOptions
+	started bool
+	count   int
+}
+
+// start writes the headers and the beginning of the document.
+func (s *streamer) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+
+	_ = SetStatusCode(s.ctx, http.StatusOK)
+
+	if s.opts.format == StreamNDJSON {
+		s.w.Header().Set("Content-Type", "application/x-ndjson")
+		s.w.WriteHeader(http.StatusOK)
+
+		return nil
+	}
+
+	s.w.Header().Set("Content-Type", "application/json")
+	s.w.WriteHeader(http.StatusOK)
+
+	prefix := `{"timestamp":` + strconv.FormatInt(time.Now().UTC().Unix(), 10)
+	if s.v.RequestID != "" {
+		id, _ := json.Marshal(s.v.RequestID)
+		prefix += `,"request_id":` + string(id)
+	}
+	prefix += `,"data":[`
+
+	return s.write([]byte(prefix))
+}
+
+// item writes an item of the collection.
+func (s *streamer) item(item interface{}) error {
+	if s.opts.format == StreamNDJSON {
+		item = Response{
+			Success:   true,
+			Timestamp: time.Now().UTC().Unix(),
+			Data:      item,
+			RequestID: s.v.RequestID,
+		}
+	}
+
+	b, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("marshal fail: %w", err)
+	}
+
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	switch {
+	case s.opts.format == StreamNDJSON:
+		b = append(b, '\n')
+	case s.count > 0:
+		b = append([]byte{','}, b...)
+	}
+
+	if err := s.write(b); err != nil {
+		return err
+	}
+
+	s.count++
+	if s.count%s.opts.flushEvery == 0 {
+		_ = s.rc.Flush()
+	}
+
+	return nil
+}
+
+// end writes the end of a successful stream.
+func (s *streamer) end() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	if s.opts.format == StreamJSON {
+		if err := s.write([]byte(`],"success":true}`)); err != nil {
+			return err
+		}
+	}
+
+	_ = s.rc.Flush()
+
+	return nil
+}
+
+// fail signals the failure of the stream. Before the stream started, the
+// error is returned as is.
+func (s *streamer) fail(err error) error {
+	if !s.started {
+		return err
+	}
+
+	_ = SetIsError(s.ctx)
+
+	// The connection is unusable once the client is gone or the write failed.
+	var we *writeError
+	if errors.Is(err, context.Canceled) || errors.As(err, &we) {
+		return nil
+	}
+
+	er := ErrorResponse{
+		Code:    CodeInternal,
+		Message: ErrInternalServer.Error(),
+	}
+	if re := GetRequestError(err); re != nil {
+		er = re.Response()
+	}
+
+	var tail []byte
+	if s.opts.format == StreamNDJSON {
+		tail, _ = json.Marshal(Response{
+			Success:   false,
+			Timestamp: time.Now().UTC().Unix(),
+			Errors:    er,
+			RequestID: s.v.RequestID,
+		})
+		tail = append(tail, '\n')
+	} else {
+		errs, _ := json.Marshal(er)
+		tail = append([]byte(`],"success":false,"errors":`), errs...)
+		tail = append(tail, '}')
+	}
+
+	_ = s.write(tail)
+	_ = s.rc.Flush()
+
+	return nil
+}
+
+// write writes the bytes to the client.
+func (s *streamer) write(b []byte) error {
+	if _, err := s.w.Write(b); err != nil {
+		return &writeError{err}
+	}
+
+	return nil
+}
+
+// writeError is returned when the response can not be written.
+type writeError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (we *writeError) Error() string {
+	return fmt.Sprintf("write fail: %v", we.err)
+}
+
+// Unwrap returns the wrapped error.
+func (we *writeError) Unwrap() error {
+	return we.err
+}
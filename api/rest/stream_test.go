@@ -0,0 +1,284 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamItem is an item of a streamed collection.
+type streamItem struct {
+	ID int `json:"id"`
+}
+
+// itemsChannel returns a closed channel holding n items.
+func itemsChannel(n int) <-chan streamItem {
+	ch := make(chan streamItem, n)
+	for i := 0; i < n; i++ {
+		ch <- streamItem{ID: i}
+	}
+	close(ch)
+
+	return ch
+}
+
+// failingIterator returns the items then fails.
+type failingIterator struct {
+	items []interface{}
+	err   error
+	pos   int
+}
+
+func (it *failingIterator) Next(ctx context.Context) bool {
+	if it.pos >= len(it.items) {
+		return false
+	}
+	it.pos++
+	return true
+}
+
+func (it *failingIterator) Value() interface{} { return it.items[it.pos-1] }
+
+func (it *failingIterator) Err() error { return it.err }
+
+func TestRespondStream(t *testing.T) {
+	newCtx := func() (context.Context, *ContextValues) {
+		v := &ContextValues{RequestID: "abc-123"}
+		return context.WithValue(context.Background(), key, v), v
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		ctx, v := newCtx()
+		rr := httptest.NewRecorder()
+
+		err := RespondStream(ctx, rr, ChannelIterator(itemsChannel(3), nil), WithFlushEvery(2))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if rr.Code != http.StatusOK || v.StatusCode != http.StatusOK {
+			t.Errorf("Expected status %v, got %v", http.StatusOK, rr.Code)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected Content-Type 'application/json', got '%s'", ct)
+		}
+		if !rr.Flushed {
+			t.Error("Expected the response to be flushed")
+		}
+
+		var resp struct {
+			Success   bool         `json:"success"`
+			Timestamp int64        `json:"timestamp"`
+			RequestID string       `json:"request_id"`
+			Data      []streamItem `json:"data"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response %s: %v", rr.Body.String(), err)
+		}
+		if !resp.Success || resp.Timestamp == 0 || resp.RequestID != "abc-123" || len(resp.Data) != 3 || resp.Data[2].ID != 2 {
+			t.Errorf("Unexpected response: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("JSON Empty", func(t *testing.T) {
+		ctx, _ := newCtx()
+		rr := httptest.NewRecorder()
+
+		if err := RespondStream(ctx, rr, ChannelIterator(itemsChannel(0), nil)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var resp Response
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response %s: %v", rr.Body.String(), err)
+		}
+		if !resp.Success {
+			t.Errorf("Unexpected response: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("JSON Failure", func(t *testing.T) {
+		ctx, v := newCtx()
+		rr := httptest.NewRecorder()
+
+		it := &failingIterator{
+			items: []interface{}{streamItem{ID: 1}},
+			err:   NewRequestError(errors.New("export expired"), http.StatusGone, "export_expired"),
+		}
+		if err := RespondStream(ctx, rr, it); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !v.IsError {
+			t.Error("Expected the request to be flagged as an error")
+		}
+
+		var resp struct {
+			Success bool          `json:"success"`
+			Data    []streamItem  `json:"data"`
+			Errors  ErrorResponse `json:"errors"`
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response %s: %v", rr.Body.String(), err)
+		}
+		if resp.Success || len(resp.Data) != 1 || resp.Errors.Code != "export_expired" {
+			t.Errorf("Unexpected response: %s", rr.Body.String())
+		}
+	})
+
+	t.Run("NDJSON", func(t *testing.T) {
+		ctx, _ := newCtx()
+		rr := httptest.NewRecorder()
+
+		if err := RespondStream(ctx, rr, ChannelIterator(itemsChannel(2), nil), WithStreamFormat(StreamNDJSON)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("Expected Content-Type 'application/x-ndjson', got '%s'", ct)
+		}
+
+		lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected 2 lines, got %d", len(lines))
+		}
+
+		var first struct {
+			Success   bool       `json:"success"`
+			RequestID string     `json:"request_id"`
+			Data      streamItem `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+			t.Fatalf("Failed to decode line %s: %v", lines[0], err)
+		}
+		if !first.Success || first.RequestID != "abc-123" || first.Data.ID != 0 {
+			t.Errorf("Unexpected first line: %s", lines[0])
+		}
+	})
+
+	t.Run("Error Before First Item", func(t *testing.T) {
+		ctx, _ := newCtx()
+		rr := httptest.NewRecorder()
+
+		errs := make(chan error, 1)
+		errs <- errors.New("database failure")
+
+		err := RespondStream(ctx, rr, ChannelIterator(make(chan streamItem), errs))
+		if err == nil || err.Error() != "database failure" {
+			t.Errorf("Expected the iteration error, got %v", err)
+		}
+		if rr.Body.Len() != 0 {
+			t.Errorf("Expected nothing to be written, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("NDJSON Failure", func(t *testing.T) {
+		ctx, _ := newCtx()
+		rr := httptest.NewRecorder()
+
+		it := &failingIterator{
+			items: []interface{}{streamItem{ID: 1}, streamItem{ID: 2}},
+			err:   errors.New("database failure"),
+		}
+		if err := RespondStream(ctx, rr, it, WithStreamFormat(StreamNDJSON)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("Expected 3 lines, got %d", len(lines))
+		}
+
+		var last struct {
+			Success bool          `json:"success"`
+			Errors  ErrorResponse `json:"errors"`
+		}
+		if err := json.Unmarshal([]byte(lines[2]), &last); err != nil {
+			t.Fatalf("Failed to decode line %s: %v", lines[2], err)
+		}
+		if last.Success || last.Errors.Code != CodeInternal || strings.Contains(lines[2], "database failure") {
+			t.Errorf("Unexpected last line: %s", lines[2])
+		}
+	})
+
+	t.Run("Context Cancellation", func(t *testing.T) {
+		ctx, _ := newCtx()
+		ctx, cancel := context.WithCancel(ctx)
+		rr := httptest.NewRecorder()
+
+		items := make(chan streamItem, 1)
+		items <- streamItem{ID: 1}
+		it := ChannelIterator(items, nil)
+
+		// Cancel once the first item is consumed.
+		wrapped := &cancelIterator{Iterator: it, cancel: cancel}
+		if err := RespondStream(ctx, rr, wrapped); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !errors.Is(it.Err(), context.Canceled) {
+			t.Errorf("Expected the iteration to be canceled, got %v", it.Err())
+		}
+	})
+}
+
+// cancelIterator cancels the context once the first item is consumed.
+type cancelIterator struct {
+	Iterator
+	cancel context.CancelFunc
+}
+
+func (it *cancelIterator) Value() interface{} {
+	it.cancel()
+	return it.Iterator.Value()
+}
+
+func TestRespondStreamWriteTimeout(t *testing.T) {
+	api := New(make(chan os.Signal, 1))
+	api.Handle(http.MethodGet, "/exports", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		// The items come slower than the write timeout of the server.
+		ch := make(chan streamItem)
+		go func() {
+			defer close(ch)
+			for i := 0; i < 4; i++ {
+				time.Sleep(30 * time.Millisecond)
+				ch <- streamItem{ID: i}
+			}
+		}()
+
+		return RespondStream(ctx, w, ChannelIterator(ch, nil), WithFlushEvery(1))
+	})
+
+	srv := httptest.NewUnstartedServer(api)
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/exports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("Expected the stream to outlast the write timeout: %v", err)
+	}
+
+	var resp struct {
+		Data []streamItem `json:"data"`
+	}
+	if err := json.Unmarshal(b, &resp); err != nil || len(resp.Data) != 4 {
+		t.Errorf("Expected the whole stream, got %s: %v", b, err)
+	}
+}
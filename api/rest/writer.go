@@ -0,0 +1,100 @@
+package rest
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// responseWriter records the status code, the bytes written, the time to
+// first byte and whether the headers were sent in the ContextValues. It
+// preserves the http.Flusher, http.Hijacker and io.ReaderFrom capabilities of
+// the wrapped writer and supports http.ResponseController.
+type responseWriter struct {
+	http.ResponseWriter
+	v     *ContextValues
+	start time.Time
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (rw *responseWriter) WriteHeader(statusCode int) {
+	// Informational responses are not the final response.
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		rw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	rw.headersSent(statusCode)
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.headersSent(http.StatusOK)
+
+	n, err := rw.ResponseWriter.Write(b)
+	rw.v.BytesWritten += int64(n)
+
+	return n, err
+}
+
+// Flush implements the http.Flusher interface.
+func (rw *responseWriter) Flush() {
+	rw.headersSent(http.StatusOK)
+
+	_ = http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// FlushError flushes and reports the failure, used by http.ResponseController.
+func (rw *responseWriter) FlushError() error {
+	rw.headersSent(http.StatusOK)
+
+	return http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Hijack implements the http.Hijacker interface. It returns
+// http.ErrNotSupported when the wrapped writer can not be hijacked.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err == nil {
+		rw.headersSent(http.StatusSwitchingProtocols)
+	}
+
+	return conn, brw, err
+}
+
+// ReadFrom implements the io.ReaderFrom interface, so the wrapped writer can
+// use its optimized copy (e.g. sendfile) when it has one.
+func (rw *responseWriter) ReadFrom(src io.Reader) (int64, error) {
+	rw.headersSent(http.StatusOK)
+
+	var n int64
+	var err error
+	if rf, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(rw.ResponseWriter, src)
+	}
+	rw.v.BytesWritten += n
+
+	return n, err
+}
+
+// Unwrap returns the wrapped writer, used by http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// headersSent records the headers are sent with the given status code, unless
+// they were already sent.
+func (rw *responseWriter) headersSent(statusCode int) {
+	if rw.v.HeadersSent {
+		return
+	}
+
+	rw.v.HeadersSent = true
+	rw.v.StatusCode = statusCode
+	rw.v.TimeToFirstByte = time.Since(rw.start)
+}
@@ -0,0 +1,146 @@
+//go:build unit
+// +build unit
+
+package rest
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestResponseWriter(t *testing.T) {
+	var got ContextValues
+
+	api := New(make(chan os.Signal, 1))
+	api.Handle(http.MethodGet, "/test", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		// Record the values once the handler is done.
+		defer func() {
+			v, _ := GetContextValues(ctx)
+			got = *v
+		}()
+
+		return mockErrorHandler(ctx, w, r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	w := httptest.NewRecorder()
+	api.ServeHTTP(w, req)
+
+	if got.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, got.StatusCode)
+	}
+	if !got.HeadersSent {
+		t.Error("Expected the headers to be sent")
+	}
+	if got.BytesWritten != int64(w.Body.Len()) {
+		t.Errorf("Expected %d bytes written, got %d", w.Body.Len(), got.BytesWritten)
+	}
+	if got.TimeToFirstByte <= 0 {
+		t.Errorf("Expected the time to first byte to be recorded, got %v", got.TimeToFirstByte)
+	}
+}
+
+func TestResponseWriterImplicitStatus(t *testing.T) {
+	v := &ContextValues{}
+	rw := &responseWriter{ResponseWriter: httptest.NewRecorder(), v: v}
+
+	n, err := rw.ReadFrom(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("ReadFrom failed: %v", err)
+	}
+	if n != 5 || v.BytesWritten != 5 {
+		t.Errorf("Expected 5 bytes written, got %d and %d", n, v.BytesWritten)
+	}
+	if v.StatusCode != http.StatusOK || !v.HeadersSent {
+		t.Errorf("Expected an implicit %d, got %d", http.StatusOK, v.StatusCode)
+	}
+
+	// The status code can not change once the headers are sent.
+	rw.WriteHeader(http.StatusNotFound)
+	if v.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, v.StatusCode)
+	}
+}
+
+func TestResponseWriterInformational(t *testing.T) {
+	v := &ContextValues{}
+	rw := &responseWriter{ResponseWriter: httptest.NewRecorder(), v: v}
+
+	rw.WriteHeader(http.StatusEarlyHints)
+	if v.HeadersSent || v.StatusCode != 0 {
+		t.Errorf("Expected an informational response to be ignored, got %+v", v)
+	}
+}
+
+func TestResponseWriterInterfaces(t *testing.T) {
+	var rw http.ResponseWriter = &responseWriter{ResponseWriter: httptest.NewRecorder(), v: &ContextValues{}}
+
+	if _, ok := rw.(http.Flusher); !ok {
+		t.Error("Expected the writer to implement http.Flusher")
+	}
+	if _, ok := rw.(http.Hijacker); !ok {
+		t.Error("Expected the writer to implement http.Hijacker")
+	}
+	if _, ok := rw.(io.ReaderFrom); !ok {
+		t.Error("Expected the writer to implement io.ReaderFrom")
+	}
+
+	// The recorder can not be hijacked.
+	if _, _, err := rw.(http.Hijacker).Hijack(); err == nil {
+		t.Error("Expected an error hijacking a recorder")
+	}
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	values := make(chan ContextValues, 1)
+
+	api := New(make(chan os.Signal, 1))
+	api.Handle(http.MethodGet, "/hijack", func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		v, _ := GetContextValues(ctx)
+		values <- *v
+
+		brw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+		brw.Flush()
+
+		return nil
+	})
+
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte("GET /hijack HTTP/1.1\r\nHost: test\r\n\r\n"))
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "ok" {
+		t.Errorf("Expected body %q, got %q", "ok", body)
+	}
+	got := <-values
+	if !got.HeadersSent || got.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("Expected the hijack to be recorded, got %+v", got)
+	}
+}
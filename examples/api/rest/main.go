@@ -6,13 +6,18 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/coderkakarrots/go-pkg-lib/api/rest"
 	"github.com/coderkakarrots/go-pkg-lib/api/rest/middleware"
 )
 
 func main() {
+	// Create a channel to listen for the interrupt signal.
 	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+
 	mw := make([]rest.Middleware, 0, 1)
 	mw = append(mw, middleware.Errors())
 	api := rest.New(shutdown, mw...)
@@ -20,11 +25,12 @@ func main() {
 	api.Handle("GET", "/hello", helloHandler)
 	api.Handle("GET", "/error-handler-simulation", errorHandler)
 
-	// Start the server (you might have a separate `Run` method in your framework)
+	// Start the server, it blocks until a shutdown signal is received.
 	port := ":8080"
 	fmt.Printf("Server listening on %s\n", port)
-	if err := http.ListenAndServe(port, api); err != nil {
-		api.SignalShutdown() // Signal shutdown in case of errors
+	if err := api.Run(context.Background(), port); err != nil {
+		fmt.Printf("Server stopped with error: %v\n", err)
+		os.Exit(1)
 	}
 }
 
@@ -21,6 +21,8 @@ type Metric struct {
 	Latency *Histogram
 	// Goroutine is to store the concurrent goroutine count.
 	Goroutine *Gauge
+	// InFlight is to store the count of requests being served.
+	InFlight *Gauge
 	// Errors is to store the error count.
 	Errors *Counter
 	// Panics is to store the panic count.
@@ -65,6 +67,11 @@ func Initialise(meterName string) (*Metric, error) {
 		return nil, err
 	}
 
+	m.InFlight, err = m.NewGauge("in_flight_request", "Gauge of all requests being served")
+	if err != nil {
+		return nil, err
+	}
+
 	m.Errors, err = m.NewCounter("error", "Incremental counter of all errors")
 	if err != nil {
 		return nil, err
@@ -6,10 +6,21 @@ package metric
 import (
 	"context"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
 
+// scrape returns the metrics exported by the handler.
+func scrape(m *Metric) string {
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	return rr.Body.String()
+}
+
 func TestMetric(t *testing.T) {
 	t.Run("AddRequest", func(t *testing.T) {
 		t.Log("Initiated Metric Handler")
@@ -23,6 +34,7 @@ func TestMetric(t *testing.T) {
 			"C": "D",
 		}
 		ctx := context.Background()
+		defer m.Shutdown(ctx)
 		m.Request.Add(ctx, 1, opt)
 	})
 
@@ -34,6 +46,7 @@ func TestMetric(t *testing.T) {
 		}
 
 		ctx := context.Background()
+		defer m.Shutdown(ctx)
 		newC, _ := m.NewCounter("concurrent_request", "concurrent request count")
 
 		newC.Add(ctx, 1, nil)
@@ -50,6 +63,7 @@ func TestMetric(t *testing.T) {
 		}
 
 		ctx := context.Background()
+		defer m.Shutdown(ctx)
 		start := time.Now()
 		elapsed := time.Since(start).Seconds()
 		m.Latency.Record(ctx, elapsed, nil)
@@ -63,6 +77,7 @@ func TestMetric(t *testing.T) {
 		}
 
 		ctx := context.Background()
+		defer m.Shutdown(ctx)
 		// Define custom bucket boundaries.
 		boundaries := []float64{0.1, 0.5, 1, 2, 5, 10}
 
@@ -81,6 +96,7 @@ func TestMetric(t *testing.T) {
 		}
 
 		ctx := context.Background()
+		defer m.Shutdown(ctx)
 		m.Goroutine.Add(ctx, 1, Attributes{
 			"handler": "TestRecordGoroutine",
 		})
@@ -90,6 +106,35 @@ func TestMetric(t *testing.T) {
 		})
 	})
 
+	t.Run("RecordInFlight", func(t *testing.T) {
+		t.Log("Initiated Metric Handler")
+		m, err := Initialise("testInFlight")
+		if err != nil {
+			t.Fatalf("failed to initialize provider: %v", err)
+		}
+
+		ctx := context.Background()
+		defer m.Shutdown(ctx)
+
+		opt := Attributes{
+			"method": "GET",
+			"path":   "/sites/{id}",
+		}
+		inFlight := `in_flight_request{method="GET",otel_scope_name="testInFlight",otel_scope_version="",path="/sites/{id}"}`
+
+		m.InFlight.Add(ctx, 1, opt)
+		m.InFlight.Add(ctx, 1, opt)
+		if body := scrape(m); !strings.Contains(body, inFlight+" 2\n") {
+			t.Errorf("Expected 2 requests in flight in:\n%s", body)
+		}
+
+		m.InFlight.Add(ctx, -1, opt)
+		m.InFlight.Add(ctx, -1, opt)
+		if body := scrape(m); !strings.Contains(body, inFlight+" 0\n") {
+			t.Errorf("Expected no request in flight in:\n%s", body)
+		}
+	})
+
 	t.Run("CustomGauge", func(t *testing.T) {
 		t.Log("Initiated Metric Handler")
 		m, err := Initialise("testMeter")
@@ -98,6 +143,7 @@ func TestMetric(t *testing.T) {
 		}
 
 		ctx := context.Background()
+		defer m.Shutdown(ctx)
 
 		newG, _ := m.NewGauge("memory_usage", "memory usage gauge")
 		// Increase gauge by 100.
@@ -15,6 +15,25 @@ type Topic struct {
 // Message is a wrapper around the pubsub message
 type Message = pubsub.Message
 
+// RequestIDAttribute is the message attribute holding the ID of the request
+// which published the message.
+const RequestIDAttribute = "request_id"
+
+// SetRequestID sets the request ID as attribute of the message so the
+// subscribers can correlate the message with the request which published it.
+// An empty request ID is ignored.
+func SetRequestID(m *Message, requestID string) {
+	if m == nil || requestID == "" {
+		return
+	}
+
+	if m.Attributes == nil {
+		m.Attributes = make(map[string]string)
+	}
+
+	m.Attributes[RequestIDAttribute] = requestID
+}
+
 // Publish sends a message to the topic
 // using the context provided
 func (t *Topic) Publish(ctx context.Context, m *Message) (string, error) {
@@ -0,0 +1,26 @@
+//go:build unit
+// +build unit
+
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRequestID(t *testing.T) {
+	msg := &Message{
+		Data: []byte("Hello, World!\n"),
+	}
+
+	SetRequestID(msg, "abc-123")
+	assert.Equal(t, "abc-123", msg.Attributes[RequestIDAttribute])
+
+	// an empty request ID keeps the existing attributes untouched
+	SetRequestID(msg, "")
+	assert.Equal(t, "abc-123", msg.Attributes[RequestIDAttribute])
+
+	// a nil message is ignored
+	assert.NotPanics(t, func() { SetRequestID(nil, "abc-123") })
+}